@@ -0,0 +1,90 @@
+package abi
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"go-finalityscraper/common/status"
+	"strings"
+)
+
+const WordLen = 32
+
+// Malformed data stays malformed, so it is not worth a retry
+func decodeErr(format string, args ...any) error {
+	return status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf(format, args...))
+}
+
+// i-th 32 byte word as uint64
+func Uint(args []byte, i int) (uint64, error) {
+	end := (i + 1) * WordLen
+	if len(args) < end {
+		return 0, decodeErr("ABI: missing argument %d", i)
+	}
+	word := args[i*WordLen : end]
+	for _, c := range word[:24] {
+		if c != 0 {
+			return 0, decodeErr("ABI: argument %d exceeds uint64", i)
+		}
+	}
+	return binary.BigEndian.Uint64(word[24:]), nil
+}
+
+// bytes argument whose offset is the i-th word
+func Bytes(args []byte, i int) ([]byte, error) {
+	offset, offset_err := Uint(args, i)
+	if offset_err != nil {
+		return nil, offset_err
+	}
+	if offset > uint64(len(args)) || offset%WordLen != 0 {
+		return nil, decodeErr("ABI: invalid offset of argument %d", i)
+	}
+	size, size_err := Uint(args[offset:], 0)
+	if size_err != nil {
+		return nil, size_err
+	}
+	start := offset + WordLen
+	if size > uint64(len(args))-start {
+		return nil, decodeErr("ABI: argument %d exceeds input", i)
+	}
+	return args[start : start+size], nil
+}
+
+// i-th word as "0x" prefixed hex, e.g. a bytes32
+func Hex(args []byte, i int) (string, error) {
+	end := (i + 1) * WordLen
+	if len(args) < end {
+		return "", decodeErr("ABI: missing argument %d", i)
+	}
+	return "0x" + hex.EncodeToString(args[i*WordLen:end]), nil
+}
+
+// i-th word as an address
+func Address(args []byte, i int) (string, error) {
+	word, word_err := Hex(args, i)
+	if word_err != nil {
+		return "", word_err
+	}
+	return "0x" + word[2+24:], nil
+}
+
+// "0x" prefixed hex, e.g. call results & log data
+func DecodeHex(s string) ([]byte, error) {
+	b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+	if err != nil {
+		return nil, decodeErr("ABI: invalid hex: %w", err)
+	}
+	return b, nil
+}
+
+// Log topic or call argument of a uint64
+func UintHex(v uint64) string {
+	word := make([]byte, WordLen)
+	binary.BigEndian.PutUint64(word[24:], v)
+	return "0x" + hex.EncodeToString(word)
+}
+
+// Log topic of an address
+func AddressHex(addr string) string {
+	return "0x" + strings.Repeat("0", 24) + strings.ToLower(strings.TrimPrefix(addr, "0x"))
+}
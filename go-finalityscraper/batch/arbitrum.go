@@ -0,0 +1,119 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+
+	"github.com/andybalholm/brotli"
+)
+
+// SequencerInbox selectors
+const (
+	// addSequencerL2BatchFromOrigin(uint256,bytes,uint256,address,uint256,uint256)
+	selector_from_origin = "8f111f3c"
+	// addSequencerL2Batch(uint256,bytes,uint256,address,uint256,uint256)
+	selector_add_batch = "e0bc9729"
+	// addSequencerL2BatchFromOrigin(uint256,bytes,uint256,address), before message counts
+	selector_from_origin_v1 = "6f12b0c9"
+	// addSequencerL2BatchFromBlobs(uint256,uint256,address,uint256,uint256), data in blobs
+	selector_from_blobs = "3e5aa082"
+)
+
+// First byte of the batch data
+const header_brotli = 0x00
+
+// Decodes SequencerInbox calldata. Every message is one L2 block after genesis,
+// so the blocks follow from prevMessageCount & newMessageCount, even for blob batches.
+func DecodeArbitrum(genesis chain.Genesis, input []byte) (Batch, error) {
+	if len(input) < 4 {
+		return Batch{}, fmt.Errorf("Input data too short")
+	}
+	selector := hex.EncodeToString(input[:4])
+	args := input[4:]
+
+	b := Batch{
+		Kind:        KindArbitrum,
+		PostingMode: chain.PostingModeCalldata,
+	}
+	// Words of prevMessageCount & newMessageCount, -1 if absent
+	counts_i := -1
+	switch selector {
+	case selector_from_origin, selector_add_batch:
+		counts_i = 4
+	case selector_from_blobs:
+		counts_i = 3
+		b.PostingMode = chain.PostingModeBlob
+	case selector_from_origin_v1:
+	default:
+		return Batch{}, fmt.Errorf("Unknown SequencerInbox selector: 0x%s", selector)
+	}
+
+	sequence_number, sequence_number_err := abi.Uint(args, 0)
+	if sequence_number_err != nil {
+		return Batch{}, sequence_number_err
+	}
+	b.SequenceNumber = sequence_number
+	var data []byte
+	if b.PostingMode == chain.PostingModeCalldata {
+		calldata, calldata_err := abi.Bytes(args, 1)
+		if calldata_err != nil {
+			return Batch{}, calldata_err
+		}
+		data = calldata
+	}
+
+	if counts_i >= 0 {
+		prev, prev_err := abi.Uint(args, counts_i)
+		if prev_err != nil {
+			return Batch{}, prev_err
+		}
+		next, next_err := abi.Uint(args, counts_i+1)
+		if next_err != nil {
+			return Batch{}, next_err
+		}
+		b.PrevMessageCount = prev
+		b.NewMessageCount = next
+		if next > prev {
+			b.Blocks = Range{
+				First: genesis.Block + prev,
+				Last:  genesis.Block + next - 1,
+			}
+			b.Complete = true
+		}
+	}
+
+	segments, segments_err := arbitrumSegments(data)
+	if segments_err != nil {
+		return b, segments_err
+	}
+	b.Segments = segments
+	return b, nil
+}
+
+// Counts the segments of brotli batch data, other headers (e.g. DAS) are not read
+func arbitrumSegments(data []byte) (int, error) {
+	if len(data) == 0 || data[0] != header_brotli {
+		return 0, nil
+	}
+	rest, rest_err := readAll(brotli.NewReader(bytes.NewReader(data[1:])))
+	if rest_err != nil {
+		return 0, fmt.Errorf("Error decompressing batch: %w", rest_err)
+	}
+
+	segments := 0
+	for len(rest) > 0 {
+		payload, next, payload_err := rlpString(rest)
+		if payload_err != nil {
+			return segments, payload_err
+		}
+		if len(payload) == 0 {
+			return segments, fmt.Errorf("Empty segment")
+		}
+		segments++
+		rest = next
+	}
+	return segments, nil
+}
@@ -0,0 +1,175 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"go-finalityscraper/common/chain"
+	"testing"
+)
+
+const test_gas_refunder = 0xe2
+
+// Static ABI words
+func words(values ...uint64) []byte {
+	b := make([]byte, 32*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint64(b[32*i+24:], v)
+	}
+	return b
+}
+
+// ABI bytes tail, length then data padded to a word
+func abiTail(data []byte) []byte {
+	padded := make([]byte, (len(data)+31)/32*32)
+	copy(padded, data)
+	return append(words(uint64(len(data))), padded...)
+}
+
+func arbitrumInput(selector string, args []byte) []byte {
+	selector_bytes, _ := hex.DecodeString(selector)
+	return append(selector_bytes, args...)
+}
+
+// Brotli batch data of segments, each an RLP string of kind ++ payload
+func arbitrumData(t *testing.T, segments ...[]byte) []byte {
+	stream := []byte{}
+	for _, segment := range segments {
+		stream = append(stream, rlpBytes(segment)...)
+	}
+	return append([]byte{header_brotli}, brotliBytes(t, stream)...)
+}
+
+// addSequencerL2BatchFromOrigin & addSequencerL2Batch
+func fromOriginArgs(sequence_number uint64, data []byte, prev uint64, next uint64) []byte {
+	head := words(sequence_number, 6*32, 1_500_000, test_gas_refunder, prev, next)
+	return append(head, abiTail(data)...)
+}
+
+func TestDecodeArbitrum(t *testing.T) {
+	genesis := testGenesis(t, "42161")
+	l2_message := append([]byte{0x03}, bytes.Repeat([]byte{0xab}, 120)...)
+	delayed := []byte{0x04, 0x01}
+	data := arbitrumData(t, l2_message, l2_message, delayed)
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  Batch
+	}{
+		{
+			name:  "from origin",
+			input: arbitrumInput(selector_from_origin, fromOriginArgs(612_345, data, 186_000_100, 186_000_110)),
+			want: Batch{
+				Blocks:           Range{First: genesis.Block + 186_000_100, Last: genesis.Block + 186_000_109},
+				Complete:         true,
+				PostingMode:      chain.PostingModeCalldata,
+				SequenceNumber:   612_345,
+				PrevMessageCount: 186_000_100,
+				NewMessageCount:  186_000_110,
+				Segments:         3,
+			},
+		},
+		{
+			name:  "add batch",
+			input: arbitrumInput(selector_add_batch, fromOriginArgs(612_346, arbitrumData(t, l2_message), 186_000_110, 186_000_111)),
+			want: Batch{
+				Blocks:           Range{First: genesis.Block + 186_000_110, Last: genesis.Block + 186_000_110},
+				Complete:         true,
+				PostingMode:      chain.PostingModeCalldata,
+				SequenceNumber:   612_346,
+				PrevMessageCount: 186_000_110,
+				NewMessageCount:  186_000_111,
+				Segments:         1,
+			},
+		},
+		{
+			// No message counts, so no blocks
+			name:  "from origin v1",
+			input: arbitrumInput(selector_from_origin_v1, append(words(41_000, 4*32, 900, test_gas_refunder), abiTail(data)...)),
+			want: Batch{
+				PostingMode:    chain.PostingModeCalldata,
+				SequenceNumber: 41_000,
+				Segments:       3,
+			},
+		},
+		{
+			name:  "from blobs",
+			input: arbitrumInput(selector_from_blobs, words(612_347, 1_500_010, test_gas_refunder, 186_000_111, 186_001_000)),
+			want: Batch{
+				Blocks:           Range{First: genesis.Block + 186_000_111, Last: genesis.Block + 186_000_999},
+				Complete:         true,
+				PostingMode:      chain.PostingModeBlob,
+				SequenceNumber:   612_347,
+				PrevMessageCount: 186_000_111,
+				NewMessageCount:  186_001_000,
+			},
+		},
+		{
+			// DAS certificates are not read
+			name:  "data availability certificate",
+			input: arbitrumInput(selector_from_origin, fromOriginArgs(612_348, []byte{0x88, 0x01, 0x02}, 186_001_000, 186_001_005)),
+			want: Batch{
+				Blocks:           Range{First: genesis.Block + 186_001_000, Last: genesis.Block + 186_001_004},
+				Complete:         true,
+				PostingMode:      chain.PostingModeCalldata,
+				SequenceNumber:   612_348,
+				PrevMessageCount: 186_001_000,
+				NewMessageCount:  186_001_005,
+			},
+		},
+		{
+			// Nothing new, e.g. a batch of delayed messages only
+			name:  "no new messages",
+			input: arbitrumInput(selector_from_origin, fromOriginArgs(612_349, arbitrumData(t, delayed), 186_001_005, 186_001_005)),
+			want: Batch{
+				PostingMode:      chain.PostingModeCalldata,
+				SequenceNumber:   612_349,
+				PrevMessageCount: 186_001_005,
+				NewMessageCount:  186_001_005,
+				Segments:         1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeArbitrum(genesis, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Kind = KindArbitrum
+			if got.Kind != tt.want.Kind || got.Blocks != tt.want.Blocks || got.Complete != tt.want.Complete ||
+				got.PostingMode != tt.want.PostingMode || got.SequenceNumber != tt.want.SequenceNumber ||
+				got.PrevMessageCount != tt.want.PrevMessageCount || got.NewMessageCount != tt.want.NewMessageCount ||
+				got.Segments != tt.want.Segments {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeArbitrumInvalid(t *testing.T) {
+	genesis := testGenesis(t, "42161")
+	data := arbitrumData(t, []byte{0x03, 0x01})
+	valid := fromOriginArgs(1, data, 10, 11)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "too short", input: []byte{0x8f, 0x11}},
+		{name: "unknown selector", input: arbitrumInput("deadbeef", valid)},
+		{name: "missing counts", input: arbitrumInput(selector_from_blobs, words(1, 2, 3))},
+		{name: "bytes past the input", input: arbitrumInput(selector_from_origin, valid[:len(valid)-32])},
+		{name: "corrupt brotli", input: arbitrumInput(selector_from_origin, fromOriginArgs(1, []byte{header_brotli, 0xff, 0xff, 0xff}, 10, 11))},
+		{name: "empty segment", input: arbitrumInput(selector_from_origin, fromOriginArgs(1, append([]byte{header_brotli}, brotliBytes(t, []byte{0x80})...), 10, 11))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeArbitrum(genesis, tt.input)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"io"
+	"strings"
+)
+
+// Decompressed batches larger than this are rejected
+const max_decompressed = 100_000_000
+
+// L2 blocks, inclusive
+type Range struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+}
+
+func (r Range) Contains(block uint64) bool {
+	return r.First <= block && block <= r.Last
+}
+
+// Smallest range containing r & other
+func (r Range) Union(other Range) Range {
+	if other.First < r.First {
+		r.First = other.First
+	}
+	if other.Last > r.Last {
+		r.Last = other.Last
+	}
+	return r
+}
+
+type Kind string
+
+const (
+	KindOptimism Kind = "optimism"
+	KindArbitrum Kind = "arbitrum"
+)
+
+// What a batch tx says about the L2, decoded from its L1 input data
+type Batch struct {
+	Kind Kind `json:"kind"`
+	// Only set if Complete
+	Blocks Range `json:"blocks"`
+	// Whether Blocks is known, OP channels may be split over several txs
+	// & blob contents are not fetched
+	Complete bool `json:"complete"`
+
+	PostingMode chain.PostingMode `json:"posting_mode"`
+	BlobHashes  []string          `json:"blob_hashes,omitempty"`
+
+	// KindOptimism
+	Frames []Frame `json:"frames,omitempty"`
+
+	// KindArbitrum
+	SequenceNumber   uint64 `json:"sequence_number,omitempty"`
+	PrevMessageCount uint64 `json:"prev_message_count,omitempty"`
+	NewMessageCount  uint64 `json:"new_message_count,omitempty"`
+	// Segments of the decompressed batch, e.g. L2 messages
+	Segments int `json:"segments,omitempty"`
+}
+
+// Whether the batch covers block, false if that is unknown
+func (b Batch) Contains(block uint64) bool {
+	return b.Complete && b.Blocks.Contains(block)
+}
+
+// From the stack of chain_id in the registry
+func MapChainIdKind(chain_id chain.ChainId) (Kind, error) {
+	c, c_err := chain.Get(chain_id)
+	if c_err != nil {
+		return "", c_err
+	}
+	switch c.Stack {
+	case chain.StackOpStack:
+		return KindOptimism, nil
+	case chain.StackArbitrum:
+		return KindArbitrum, nil
+	default:
+		return "", fmt.Errorf("No batches on chain: %s", chain_id)
+	}
+}
+
+// Decodes the input data of a batch tx of chain_id
+func Decode(chain_id chain.ChainId, input []byte) (Batch, error) {
+	kind, kind_err := MapChainIdKind(chain_id)
+	if kind_err != nil {
+		return Batch{}, kind_err
+	}
+	if kind == KindOptimism {
+		op_stack, op_stack_err := chain.MapChainIdOpStack(chain_id)
+		if op_stack_err == nil && op_stack.NoBatchDecoding {
+			return Batch{}, fmt.Errorf("Batches of chain %s are not decoded", chain_id)
+		}
+	}
+	genesis, genesis_err := chain.MapChainIdGenesis(chain_id)
+	if genesis_err != nil {
+		return Batch{}, genesis_err
+	}
+	switch kind {
+	case KindArbitrum:
+		return DecodeArbitrum(genesis, input)
+	default:
+		return DecodeOptimism(genesis, input)
+	}
+}
+
+// Like Decode, for "0x" prefixed input data
+func DecodeHex(chain_id chain.ChainId, input string) (Batch, error) {
+	input_bytes, input_err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if input_err != nil {
+		return Batch{}, fmt.Errorf("Invalid input data: %w", input_err)
+	}
+	return Decode(chain_id, input_bytes)
+}
+
+// Like DecodeHex, for txs of any type. OP blob txs carry no calldata,
+// so only their blobs are known.
+func DecodeTx(chain_id chain.ChainId, tx_type uint64, input string, blob_hashes []string) (Batch, error) {
+	mode := chain.PostingModeOf(tx_type)
+	if mode == chain.PostingModeBlob {
+		kind, kind_err := MapChainIdKind(chain_id)
+		if kind_err != nil {
+			return Batch{}, kind_err
+		}
+		if kind == KindOptimism {
+			return Batch{
+				Kind:        kind,
+				PostingMode: mode,
+				BlobHashes:  blob_hashes,
+			}, nil
+		}
+	}
+
+	b, b_err := DecodeHex(chain_id, input)
+	if b_err != nil {
+		return b, b_err
+	}
+	// Arbitrum tells blob batches by selector too
+	if mode == chain.PostingModeBlob {
+		b.PostingMode = mode
+	}
+	b.BlobHashes = blob_hashes
+	return b, nil
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	n, err := io.Copy(buf, io.LimitReader(r, max_decompressed+1))
+	if err != nil {
+		return nil, err
+	}
+	if n > max_decompressed {
+		return nil, fmt.Errorf("Decompressed batch exceeds %d bytes", max_decompressed)
+	}
+	return buf.Bytes(), nil
+}
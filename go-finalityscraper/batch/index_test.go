@@ -0,0 +1,101 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/hex"
+	"go-finalityscraper/common/chain"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Encoders for the fixtures, the inverse of rlp.go & the decompression
+
+func rlpHeader(offset byte, size int) []byte {
+	if size < 56 {
+		return []byte{offset + byte(size)}
+	}
+	size_bytes := []byte{}
+	for s := size; s > 0; s >>= 8 {
+		size_bytes = append([]byte{byte(s)}, size_bytes...)
+	}
+	return append([]byte{offset + 55 + byte(len(size_bytes))}, size_bytes...)
+}
+
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+func rlpUintBytes(v uint64) []byte {
+	b := []byte{}
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return rlpBytes(b)
+}
+
+func rlpListBytes(items ...[]byte) []byte {
+	payload := bytes.Join(items, nil)
+	return append(rlpHeader(0xc0, len(payload)), payload...)
+}
+
+func brotliBytes(t *testing.T, b []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := brotli.NewWriter(buf)
+	_, err := w.Write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testGenesis(t *testing.T, chain_id chain.ChainId) chain.Genesis {
+	genesis, err := chain.MapChainIdGenesis(chain_id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return genesis
+}
+
+func TestDecodeTx(t *testing.T) {
+	blob_hashes := []string{"0x01aa", "0x01bb"}
+
+	// OP blob txs have no calldata to read
+	b, err := DecodeTx("10", 3, "0x", blob_hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Kind != KindOptimism || b.Complete || b.PostingMode != chain.PostingModeBlob || len(b.BlobHashes) != 2 {
+		t.Fatalf("got %+v", b)
+	}
+
+	// Arbitrum blob txs still carry the message counts
+	input := arbitrumInput(selector_from_blobs, words(7, 1, 0, 10, 20))
+	b, err = DecodeTx("42161", 3, "0x"+hex.EncodeToString(input), blob_hashes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	genesis := testGenesis(t, "42161")
+	want := Range{First: genesis.Block + 10, Last: genesis.Block + 19}
+	if b.Kind != KindArbitrum || !b.Complete || b.Blocks != want || b.PostingMode != chain.PostingModeBlob || len(b.BlobHashes) != 2 {
+		t.Fatalf("got %+v", b)
+	}
+
+	_, err = DecodeTx("1", 2, "0x00", nil)
+	if err == nil {
+		t.Fatal("got no error for a chain without batches")
+	}
+
+	_, err = DecodeTx("204", 2, "0x00", nil)
+	if err == nil || !strings.Contains(err.Error(), "not decoded") {
+		t.Fatalf("got error %v for a chain with no_batch_decoding", err)
+	}
+}
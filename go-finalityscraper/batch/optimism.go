@@ -0,0 +1,246 @@
+package batch
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"io"
+	"sort"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Batcher tx input data: version byte, then frames
+const derivation_version_0 = 0x00
+
+const channel_id_len = 16
+
+// Channel compression, zlib is recognized by its CMF byte
+const channel_version_brotli = 0x01
+
+const (
+	batch_type_singular = 0x00
+	batch_type_span     = 0x01
+)
+
+// Part of a channel, channels may be split over several txs
+type Frame struct {
+	ChannelId string `json:"channel_id"`
+	Number    uint16 `json:"number"`
+	IsLast    bool   `json:"is_last"`
+	Data      []byte `json:"-"`
+}
+
+// channel_id ++ frame_number (uint16) ++ frame_data_length (uint32) ++ frame_data ++ is_last (byte)
+func ParseFrames(input []byte) ([]Frame, error) {
+	if len(input) == 0 || input[0] != derivation_version_0 {
+		return nil, fmt.Errorf("Unknown derivation version")
+	}
+	rest := input[1:]
+	frames := []Frame{}
+	for len(rest) > 0 {
+		if len(rest) < channel_id_len+2+4 {
+			return nil, fmt.Errorf("Frame header truncated")
+		}
+		frame := Frame{
+			ChannelId: "0x" + hex.EncodeToString(rest[:channel_id_len]),
+			Number:    binary.BigEndian.Uint16(rest[channel_id_len:]),
+		}
+		data_len := binary.BigEndian.Uint32(rest[channel_id_len+2:])
+		rest = rest[channel_id_len+2+4:]
+		if uint64(data_len)+1 > uint64(len(rest)) {
+			return nil, fmt.Errorf("Frame data truncated")
+		}
+		frame.Data = rest[:data_len]
+		switch rest[data_len] {
+		case 0:
+		case 1:
+			frame.IsLast = true
+		default:
+			return nil, fmt.Errorf("Invalid is_last byte")
+		}
+		rest = rest[data_len+1:]
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+// Decodes OP Stack batcher frames. Blocks are only known
+// if every channel of the tx is complete within it.
+func DecodeOptimism(genesis chain.Genesis, input []byte) (Batch, error) {
+	frames, frames_err := ParseFrames(input)
+	if frames_err != nil {
+		return Batch{}, frames_err
+	}
+	b := Batch{
+		Kind:        KindOptimism,
+		PostingMode: chain.PostingModeCalldata,
+		Frames:      frames,
+	}
+
+	channels := map[string][]Frame{}
+	channel_ids := []string{}
+	for _, frame := range frames {
+		_, channel_exists := channels[frame.ChannelId]
+		if !channel_exists {
+			channel_ids = append(channel_ids, frame.ChannelId)
+		}
+		channels[frame.ChannelId] = append(channels[frame.ChannelId], frame)
+	}
+
+	for i, channel_id := range channel_ids {
+		data, data_ok := channelData(channels[channel_id])
+		if !data_ok {
+			return b, nil
+		}
+		blocks, blocks_err := decodeChannel(genesis, data)
+		if blocks_err != nil {
+			return b, fmt.Errorf("Error decoding channel %s: %w", channel_id, blocks_err)
+		}
+		if i == 0 {
+			b.Blocks = blocks
+		} else {
+			b.Blocks = b.Blocks.Union(blocks)
+		}
+	}
+	b.Complete = len(channel_ids) > 0
+	return b, nil
+}
+
+// Frame data in order, false unless frames 0..last are all there
+func channelData(frames []Frame) ([]byte, bool) {
+	sort.Slice(frames, func(i, j int) bool {
+		return frames[i].Number < frames[j].Number
+	})
+	data := []byte{}
+	for i, frame := range frames {
+		if int(frame.Number) != i {
+			return nil, false
+		}
+		data = append(data, frame.Data...)
+	}
+	return data, frames[len(frames)-1].IsLast
+}
+
+func decompressChannel(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("Empty channel")
+	}
+	// zlib CMF: deflate, or the reserved 0xf
+	if data[0]&0x0f == 8 || data[0]&0x0f == 15 {
+		r, r_err := zlib.NewReader(bytes.NewReader(data))
+		if r_err != nil {
+			return nil, r_err
+		}
+		defer r.Close()
+		return readAll(r)
+	}
+	if data[0] == channel_version_brotli {
+		return readAll(brotli.NewReader(bytes.NewReader(data[1:])))
+	}
+	return nil, fmt.Errorf("Unknown channel compression %#x", data[0])
+}
+
+// Blocks of the batches in a channel, an RLP stream of batches
+func decodeChannel(genesis chain.Genesis, data []byte) (Range, error) {
+	rest, rest_err := decompressChannel(data)
+	if rest_err != nil {
+		return Range{}, rest_err
+	}
+
+	var blocks Range
+	found := false
+	for len(rest) > 0 {
+		payload, next, payload_err := rlpString(rest)
+		if payload_err != nil {
+			return Range{}, payload_err
+		}
+		rest = next
+		if len(payload) == 0 {
+			return Range{}, fmt.Errorf("Empty batch")
+		}
+
+		var batch_blocks Range
+		switch payload[0] {
+		case batch_type_singular:
+			block, block_err := singularBatchBlock(genesis, payload[1:])
+			if block_err != nil {
+				return Range{}, block_err
+			}
+			batch_blocks = Range{First: block, Last: block}
+		case batch_type_span:
+			span_blocks, span_err := spanBatchBlocks(genesis, payload[1:])
+			if span_err != nil {
+				return Range{}, span_err
+			}
+			batch_blocks = span_blocks
+		default:
+			return Range{}, fmt.Errorf("Unknown batch type %#x", payload[0])
+		}
+
+		if found {
+			blocks = blocks.Union(batch_blocks)
+		} else {
+			blocks = batch_blocks
+			found = true
+		}
+	}
+	if !found {
+		return Range{}, fmt.Errorf("No batches in channel")
+	}
+	return blocks, nil
+}
+
+// rlp([parent_hash, epoch_number, epoch_hash, timestamp, transaction_list])
+func singularBatchBlock(genesis chain.Genesis, data []byte) (uint64, error) {
+	is_list, payload, _, err := rlpItem(data)
+	if err != nil {
+		return 0, err
+	}
+	if !is_list {
+		return 0, fmt.Errorf("Singular batch is not a list")
+	}
+	items, items_err := rlpList(payload)
+	if items_err != nil {
+		return 0, items_err
+	}
+	if len(items) < 4 {
+		return 0, fmt.Errorf("Singular batch has %d fields", len(items))
+	}
+	ts, ts_err := rlpUint(items[3])
+	if ts_err != nil {
+		return 0, ts_err
+	}
+	return genesis.BlockAt(int64(ts)), nil
+}
+
+// prefix: rel_timestamp, l1_origin_num (uvarints), parent_check, l1_origin_check (20 bytes each)
+// payload: block_count (uvarint), ...
+func spanBatchBlocks(genesis chain.Genesis, data []byte) (Range, error) {
+	r := bytes.NewReader(data)
+	rel_ts, rel_ts_err := binary.ReadUvarint(r)
+	if rel_ts_err != nil {
+		return Range{}, fmt.Errorf("Span batch rel_timestamp: %w", rel_ts_err)
+	}
+	_, l1_origin_err := binary.ReadUvarint(r)
+	if l1_origin_err != nil {
+		return Range{}, fmt.Errorf("Span batch l1_origin_num: %w", l1_origin_err)
+	}
+	_, skip_err := r.Seek(20+20, io.SeekCurrent)
+	if skip_err != nil {
+		return Range{}, skip_err
+	}
+	block_count, block_count_err := binary.ReadUvarint(r)
+	if block_count_err != nil {
+		return Range{}, fmt.Errorf("Span batch block_count: %w", block_count_err)
+	}
+	if block_count == 0 {
+		return Range{}, fmt.Errorf("Span batch without blocks")
+	}
+
+	first := genesis.BlockAt(genesis.Time + int64(rel_ts))
+	return Range{First: first, Last: first + block_count - 1}, nil
+}
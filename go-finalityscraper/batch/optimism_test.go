@@ -0,0 +1,179 @@
+package batch
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"go-finalityscraper/common/chain"
+	"testing"
+)
+
+func zlibBytes(t *testing.T, b []byte) []byte {
+	buf := &bytes.Buffer{}
+	w := zlib.NewWriter(buf)
+	_, err := w.Write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func testChannelId(n byte) []byte {
+	return bytes.Repeat([]byte{n}, channel_id_len)
+}
+
+// channel_id ++ frame_number ++ frame_data_length ++ frame_data ++ is_last
+func frameBytes(channel_id []byte, number uint16, data []byte, is_last bool) []byte {
+	b := append([]byte{}, channel_id...)
+	b = binary.BigEndian.AppendUint16(b, number)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(data)))
+	b = append(b, data...)
+	if is_last {
+		return append(b, 1)
+	}
+	return append(b, 0)
+}
+
+func opInput(frames ...[]byte) []byte {
+	return append([]byte{derivation_version_0}, bytes.Join(frames, nil)...)
+}
+
+// Channel of batches, each an RLP string of batch_type ++ payload
+func channelBytes(batches ...[]byte) []byte {
+	stream := []byte{}
+	for _, b := range batches {
+		stream = append(stream, rlpBytes(b)...)
+	}
+	return stream
+}
+
+// batch_type_singular ++ rlp([parent_hash, epoch_number, epoch_hash, timestamp, transaction_list])
+func singularBatch(ts uint64) []byte {
+	hash := bytes.Repeat([]byte{0x11}, 32)
+	tx := append([]byte{0x02}, bytes.Repeat([]byte{0x22}, 90)...)
+	return append([]byte{batch_type_singular}, rlpListBytes(
+		rlpBytes(hash),
+		rlpUintBytes(19_000_000),
+		rlpBytes(hash),
+		rlpUintBytes(ts),
+		rlpListBytes(rlpBytes(tx)),
+	)...)
+}
+
+// batch_type_span ++ rel_timestamp ++ l1_origin_num ++ parent_check ++ l1_origin_check ++ block_count ++ ...
+func spanBatch(rel_ts uint64, block_count uint64) []byte {
+	b := []byte{batch_type_span}
+	b = binary.AppendUvarint(b, rel_ts)
+	b = binary.AppendUvarint(b, 19_000_000)
+	b = append(b, bytes.Repeat([]byte{0x33}, 40)...)
+	b = binary.AppendUvarint(b, block_count)
+	// origin_bits, block_tx_counts & txs, not read
+	return append(b, bytes.Repeat([]byte{0x44}, 64)...)
+}
+
+func TestDecodeOptimism(t *testing.T) {
+	genesis := testGenesis(t, "10")
+	// L2 block n after genesis
+	ts := func(n uint64) uint64 {
+		return uint64(genesis.Time) + n*uint64(genesis.BlockTime)
+	}
+	singular := zlibBytes(t, channelBytes(singularBatch(ts(18_000_000)), singularBatch(ts(18_000_001)), singularBatch(ts(18_000_002))))
+	span := append([]byte{channel_version_brotli}, brotliBytes(t, channelBytes(spanBatch(ts(18_000_100)-uint64(genesis.Time), 250)))...)
+
+	tests := []struct {
+		name   string
+		input  []byte
+		want   Batch
+		frames int
+	}{
+		{
+			name:   "singular batches",
+			input:  opInput(frameBytes(testChannelId(1), 0, singular, true)),
+			want:   Batch{Blocks: Range{First: genesis.Block + 18_000_000, Last: genesis.Block + 18_000_002}, Complete: true},
+			frames: 1,
+		},
+		{
+			name:   "span batch",
+			input:  opInput(frameBytes(testChannelId(2), 0, span, true)),
+			want:   Batch{Blocks: Range{First: genesis.Block + 18_000_100, Last: genesis.Block + 18_000_349}, Complete: true},
+			frames: 1,
+		},
+		{
+			// Out of order within the tx
+			name: "channel over frames",
+			input: opInput(
+				frameBytes(testChannelId(2), 1, span[20:], true),
+				frameBytes(testChannelId(2), 0, span[:20], false),
+			),
+			want:   Batch{Blocks: Range{First: genesis.Block + 18_000_100, Last: genesis.Block + 18_000_349}, Complete: true},
+			frames: 2,
+		},
+		{
+			name: "channels",
+			input: opInput(
+				frameBytes(testChannelId(1), 0, singular, true),
+				frameBytes(testChannelId(2), 0, span, true),
+			),
+			want:   Batch{Blocks: Range{First: genesis.Block + 18_000_000, Last: genesis.Block + 18_000_349}, Complete: true},
+			frames: 2,
+		},
+		{
+			// The rest of the channel is in a later tx
+			name:   "first frame only",
+			input:  opInput(frameBytes(testChannelId(3), 0, span[:20], false)),
+			frames: 1,
+		},
+		{
+			name:   "last frame only",
+			input:  opInput(frameBytes(testChannelId(3), 1, span[20:], true)),
+			frames: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeOptimism(genesis, tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.want.Kind = KindOptimism
+			tt.want.PostingMode = chain.PostingModeCalldata
+			if got.Kind != tt.want.Kind || got.Blocks != tt.want.Blocks || got.Complete != tt.want.Complete ||
+				got.PostingMode != tt.want.PostingMode || len(got.Frames) != tt.frames ||
+				got.SequenceNumber != 0 || got.Segments != 0 {
+				t.Fatalf("got %+v, want %+v with %d frames", got, tt.want, tt.frames)
+			}
+		})
+	}
+}
+
+func TestDecodeOptimismInvalid(t *testing.T) {
+	genesis := testGenesis(t, "10")
+	channel := zlibBytes(t, channelBytes(singularBatch(uint64(genesis.Time))))
+	frame := frameBytes(testChannelId(1), 0, channel, true)
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty", input: []byte{}},
+		{name: "unknown version", input: append([]byte{0x01}, frame...)},
+		{name: "truncated header", input: opInput(frame[:channel_id_len])},
+		{name: "truncated data", input: opInput(frame[:len(frame)-2])},
+		{name: "invalid is_last", input: opInput(append(frame[:len(frame)-1:len(frame)-1], 2))},
+		{name: "unknown compression", input: opInput(frameBytes(testChannelId(1), 0, []byte{0x02, 0x00}, true))},
+		{name: "unknown batch type", input: opInput(frameBytes(testChannelId(1), 0, zlibBytes(t, channelBytes([]byte{0x07, 0x00})), true))},
+		{name: "span batch without blocks", input: opInput(frameBytes(testChannelId(1), 0, zlibBytes(t, channelBytes(spanBatch(0, 0))), true))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeOptimism(genesis, tt.input)
+			if err == nil {
+				t.Fatal("got no error")
+			}
+		})
+	}
+}
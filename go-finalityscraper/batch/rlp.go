@@ -0,0 +1,93 @@
+package batch
+
+import "fmt"
+
+// One RLP item at the start of b: whether it is a list, its payload, and what follows it
+func rlpItem(b []byte) (bool, []byte, []byte, error) {
+	if len(b) == 0 {
+		return false, nil, nil, fmt.Errorf("RLP: empty input")
+	}
+	prefix := b[0]
+	switch {
+	case prefix < 0x80:
+		return false, b[:1], b[1:], nil
+	case prefix < 0xb8:
+		return rlpSplit(false, b, 1, uint64(prefix-0x80))
+	case prefix < 0xc0:
+		size, size_err := rlpSize(b, int(prefix-0xb7))
+		if size_err != nil {
+			return false, nil, nil, size_err
+		}
+		return rlpSplit(false, b, 1+int(prefix-0xb7), size)
+	case prefix < 0xf8:
+		return rlpSplit(true, b, 1, uint64(prefix-0xc0))
+	default:
+		size, size_err := rlpSize(b, int(prefix-0xf7))
+		if size_err != nil {
+			return false, nil, nil, size_err
+		}
+		return rlpSplit(true, b, 1+int(prefix-0xf7), size)
+	}
+}
+
+// Big endian size of size_len bytes after the prefix
+func rlpSize(b []byte, size_len int) (uint64, error) {
+	if size_len > 8 || len(b) < 1+size_len {
+		return 0, fmt.Errorf("RLP: invalid size")
+	}
+	size := uint64(0)
+	for _, c := range b[1 : 1+size_len] {
+		size = size<<8 | uint64(c)
+	}
+	return size, nil
+}
+
+func rlpSplit(is_list bool, b []byte, offset int, size uint64) (bool, []byte, []byte, error) {
+	if size > uint64(len(b)-offset) {
+		return false, nil, nil, fmt.Errorf("RLP: item of %d bytes exceeds input", size)
+	}
+	end := offset + int(size)
+	return is_list, b[offset:end], b[end:], nil
+}
+
+// Payload of an RLP string
+func rlpString(b []byte) ([]byte, []byte, error) {
+	is_list, payload, rest, err := rlpItem(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if is_list {
+		return nil, nil, fmt.Errorf("RLP: expected string, got list")
+	}
+	return payload, rest, nil
+}
+
+// Raw items of an RLP list payload
+func rlpList(payload []byte) ([][]byte, error) {
+	items := [][]byte{}
+	for len(payload) > 0 {
+		_, _, rest, err := rlpItem(payload)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, payload[:len(payload)-len(rest)])
+		payload = rest
+	}
+	return items, nil
+}
+
+// Big endian uint of an RLP string item
+func rlpUint(item []byte) (uint64, error) {
+	payload, _, err := rlpString(item)
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) > 8 {
+		return 0, fmt.Errorf("RLP: uint of %d bytes", len(payload))
+	}
+	v := uint64(0)
+	for _, c := range payload {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
@@ -0,0 +1,164 @@
+package beacon
+
+import (
+	"context"
+	"go-finalityscraper/common/status"
+	"strconv"
+	"sync"
+)
+
+type checkpointKind int
+
+const (
+	kindJustified checkpointKind = iota
+	kindFinalized
+)
+
+func (kind checkpointKind) epoch(checkpoints FinalityCheckpoints) uint64 {
+	if kind == kindJustified {
+		return uint64(checkpoints.CurrentJustified.Epoch)
+	}
+	return uint64(checkpoints.Finalized.Epoch)
+}
+
+// When epochs were justified & finalized. Checkpoints only move at epoch
+// boundaries, so that is the first epoch whose start state has them.
+type Finality struct {
+	c *Client
+
+	mu *sync.Mutex
+	// unix s, 0 until fetched
+	genesis int64
+	// Checkpoint epoch -> unix ms it was reached at
+	times [2]map[uint64]int64
+}
+
+func NewFinality(c *Client) *Finality {
+	return &Finality{
+		c:     c,
+		mu:    &sync.Mutex{},
+		times: [2]map[uint64]int64{{}, {}},
+	}
+}
+
+// Justified & finalized times (unix ms) of the L1 block at ts (unix ms), 0 while not reached
+func (f *Finality) BlockTimes(ctx context.Context, ts int64) (int64, int64, error) {
+	genesis, genesis_err := f.genesisTime(ctx)
+	if genesis_err != nil {
+		return 0, 0, genesis_err
+	}
+	if ts/1000 < genesis {
+		return 0, 0, nil
+	}
+	slot := uint64(ts/1000-genesis) / SecondsPerSlot
+	// First checkpoint at or after the block
+	epoch := (slot + SlotsPerEpoch - 1) / SlotsPerEpoch
+
+	justified, justified_err := f.reachedAt(ctx, kindJustified, epoch)
+	if justified_err != nil || justified == 0 {
+		return 0, 0, justified_err
+	}
+	finalized, finalized_err := f.reachedAt(ctx, kindFinalized, epoch)
+	if finalized_err != nil {
+		return justified, 0, finalized_err
+	}
+	return justified, finalized, nil
+}
+
+func (f *Finality) genesisTime(ctx context.Context) (int64, error) {
+	f.mu.Lock()
+	genesis := f.genesis
+	f.mu.Unlock()
+	if genesis != 0 {
+		return genesis, nil
+	}
+
+	res, res_err := f.c.Genesis(ctx)
+	if res_err != nil {
+		return 0, res_err
+	}
+	f.mu.Lock()
+	f.genesis = int64(res.GenesisTime)
+	f.mu.Unlock()
+	return int64(res.GenesisTime), nil
+}
+
+// unix ms the checkpoint of epoch was reached at, 0 if it was not yet
+func (f *Finality) reachedAt(ctx context.Context, kind checkpointKind, epoch uint64) (int64, error) {
+	f.mu.Lock()
+	t, t_exists := f.times[kind][epoch]
+	f.mu.Unlock()
+	if t_exists {
+		return t, nil
+	}
+
+	head, head_err := f.c.FinalityCheckpoints(ctx, "head")
+	if head_err != nil {
+		return 0, head_err
+	}
+	if kind.epoch(head) < epoch {
+		return 0, nil
+	}
+	header, header_err := f.c.Header(ctx, "head")
+	if header_err != nil {
+		return 0, header_err
+	}
+	head_epoch := uint64(header.Header.Message.Slot) / SlotsPerEpoch
+
+	// Head has it, so hi does. Usually reached 1 or 2 epochs later,
+	// so those are probed first, then gaps grow
+	lo := epoch + 1
+	hi := max(head_epoch, lo)
+	gap := uint64(0)
+	for lo+gap < hi {
+		probe := lo + gap
+		reached, reached_err := f.reachedBy(ctx, kind, epoch, probe)
+		if reached_err != nil {
+			return 0, reached_err
+		}
+		if reached {
+			hi = probe
+			break
+		}
+		lo = probe + 1
+		if probe > epoch+1 {
+			gap = gap*2 + 1
+		}
+	}
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		reached, reached_err := f.reachedBy(ctx, kind, epoch, mid)
+		if reached_err != nil {
+			return 0, reached_err
+		}
+		if reached {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	f.mu.Lock()
+	t = (f.genesis + int64(hi*SlotsPerEpoch*SecondsPerSlot)) * 1000
+	f.times[kind][epoch] = t
+	f.mu.Unlock()
+	return t, nil
+}
+
+// Whether the start state of at_epoch has the checkpoint of epoch. Some nodes
+// have no state for a missed slot, later slots of the epoch have the same checkpoints.
+func (f *Finality) reachedBy(ctx context.Context, kind checkpointKind, epoch uint64, at_epoch uint64) (bool, error) {
+	var checkpoints_err error
+	for slot := at_epoch * SlotsPerEpoch; slot < (at_epoch+1)*SlotsPerEpoch; slot++ {
+		checkpoints, err := f.c.FinalityCheckpoints(ctx, strconv.FormatUint(slot, 10))
+		if status.CodeOf(err) == status.ErrCodeNotFound {
+			checkpoints_err = err
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		return kind.epoch(checkpoints) >= epoch, nil
+	}
+	return false, checkpoints_err
+}
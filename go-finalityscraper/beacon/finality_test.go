@@ -0,0 +1,221 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const test_genesis = 1_606_824_023
+
+const epoch_seconds = SlotsPerEpoch * SecondsPerSlot
+
+// Local stand-in for a beacon node at head_epoch. The start state of epoch e has
+// justified(e) & finalized(e) as checkpoints, missed slots have no state.
+type testChain struct {
+	head_epoch uint64
+	justified  func(e uint64) uint64
+	finalized  func(e uint64) uint64
+	missed     map[uint64]bool
+
+	mu *sync.Mutex
+	// State ids read
+	states []string
+}
+
+// Justified the next epoch & finalized the one after, as without forks
+func newTestChain(head_epoch uint64) *testChain {
+	return &testChain{
+		head_epoch: head_epoch,
+		justified:  func(e uint64) uint64 { return e - 1 },
+		finalized:  func(e uint64) uint64 { return e - 2 },
+		missed:     map[uint64]bool{},
+		mu:         &sync.Mutex{},
+	}
+}
+
+// As the API encodes them, epochs in decimal strings
+func (tc *testChain) checkpoints(e uint64) any {
+	checkpoint := func(epoch uint64) map[string]string {
+		return map[string]string{"epoch": strconv.FormatUint(epoch, 10), "root": "0x00"}
+	}
+	return map[string]any{
+		"previous_justified": checkpoint(tc.justified(e) - 1),
+		"current_justified":  checkpoint(tc.justified(e)),
+		"finalized":          checkpoint(tc.finalized(e)),
+	}
+}
+
+func (tc *testChain) serve(t *testing.T) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data any
+		switch {
+		case r.URL.Path == "/eth/v1/beacon/genesis":
+			data = map[string]string{"genesis_time": strconv.Itoa(test_genesis)}
+		case r.URL.Path == "/eth/v1/beacon/headers/head":
+			data = map[string]any{"header": map[string]any{"message": map[string]string{
+				"slot": strconv.FormatUint(tc.head_epoch*SlotsPerEpoch+7, 10),
+			}}}
+		case strings.HasSuffix(r.URL.Path, "/finality_checkpoints"):
+			state_id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/eth/v1/beacon/states/"), "/finality_checkpoints")
+			tc.mu.Lock()
+			tc.states = append(tc.states, state_id)
+			tc.mu.Unlock()
+			if state_id == "head" {
+				data = tc.checkpoints(tc.head_epoch)
+				break
+			}
+			slot, slot_err := strconv.ParseUint(state_id, 10, 64)
+			if slot_err != nil || tc.missed[slot] || slot/SlotsPerEpoch > tc.head_epoch {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			data = tc.checkpoints(slot / SlotsPerEpoch)
+		default:
+			t.Errorf("Unexpected call: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL)
+}
+
+// unix ms of the start of epoch
+func epochMs(epoch uint64) int64 {
+	return (test_genesis + int64(epoch)*epoch_seconds) * 1000
+}
+
+// unix ms of the block at slot
+func slotMs(slot uint64) int64 {
+	return (test_genesis + int64(slot)*SecondsPerSlot) * 1000
+}
+
+func TestBlockTimes(t *testing.T) {
+	tests := []struct {
+		name           string
+		chain          *testChain
+		ts             int64
+		want_justified int64
+		want_finalized int64
+	}{
+		{
+			// Justified with its own epoch
+			name:           "slot on an epoch boundary",
+			chain:          newTestChain(300),
+			ts:             slotMs(100 * SlotsPerEpoch),
+			want_justified: epochMs(101),
+			want_finalized: epochMs(102),
+		},
+		{
+			// Justified with the next epoch
+			name:           "slot mid-epoch",
+			chain:          newTestChain(300),
+			ts:             slotMs(100*SlotsPerEpoch + 5),
+			want_justified: epochMs(102),
+			want_finalized: epochMs(103),
+		},
+		{
+			name:           "not finalized yet",
+			chain:          newTestChain(102),
+			ts:             slotMs(100*SlotsPerEpoch + 5),
+			want_justified: epochMs(102),
+		},
+		{
+			name:  "not justified yet",
+			chain: newTestChain(101),
+			ts:    slotMs(100*SlotsPerEpoch + 5),
+		},
+		{
+			name:  "before genesis",
+			chain: newTestChain(300),
+			ts:    (test_genesis - 60) * 1000,
+		},
+		{
+			// Nothing justified from epoch 100 to 140, the gaps have to grow
+			name: "finality delayed",
+			chain: &testChain{
+				head_epoch: 300,
+				justified: func(e uint64) uint64 {
+					if e > 99 && e < 141 {
+						return 98
+					}
+					return e - 1
+				},
+				finalized: func(e uint64) uint64 {
+					if e > 99 && e < 142 {
+						return 97
+					}
+					return e - 2
+				},
+				missed: map[uint64]bool{},
+				mu:     &sync.Mutex{},
+			},
+			ts:             slotMs(100*SlotsPerEpoch + 5),
+			want_justified: epochMs(141),
+			want_finalized: epochMs(142),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFinality(tt.chain.serve(t))
+
+			justified, finalized, err := f.BlockTimes(context.Background(), tt.ts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if justified != tt.want_justified || finalized != tt.want_finalized {
+				t.Fatalf("got %d, %d, want %d, %d", justified, finalized, tt.want_justified, tt.want_finalized)
+			}
+		})
+	}
+}
+
+func TestBlockTimesMissedSlot(t *testing.T) {
+	tc := newTestChain(300)
+	// Boundary slots of the epochs the checkpoints are reached at
+	tc.missed[102*SlotsPerEpoch] = true
+	tc.missed[103*SlotsPerEpoch] = true
+	tc.missed[103*SlotsPerEpoch+1] = true
+	f := NewFinality(tc.serve(t))
+
+	justified, finalized, err := f.BlockTimes(context.Background(), slotMs(100*SlotsPerEpoch+5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if justified != epochMs(102) || finalized != epochMs(103) {
+		t.Fatalf("got %d, %d, want %d, %d", justified, finalized, epochMs(102), epochMs(103))
+	}
+	read := map[string]bool{}
+	for _, state_id := range tc.states {
+		read[state_id] = true
+	}
+	if !read[strconv.Itoa(102*SlotsPerEpoch+1)] || !read[strconv.Itoa(103*SlotsPerEpoch+2)] {
+		t.Fatalf("got states %v, want the slots after the missed ones", tc.states)
+	}
+}
+
+func TestBlockTimesCached(t *testing.T) {
+	tc := newTestChain(300)
+	f := NewFinality(tc.serve(t))
+	ts := slotMs(100*SlotsPerEpoch + 5)
+
+	_, _, err := f.BlockTimes(context.Background(), ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc.states = nil
+	justified, finalized, err := f.BlockTimes(context.Background(), ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if justified != epochMs(102) || finalized != epochMs(103) || len(tc.states) != 0 {
+		t.Fatalf("got %d, %d after reading %v", justified, finalized, tc.states)
+	}
+}
@@ -0,0 +1,134 @@
+package beacon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const call_timeout = 30 * time.Second
+
+// Mainnet & testnet presets
+const (
+	SlotsPerEpoch  = 32
+	SecondsPerSlot = 12
+)
+
+// Decimal string, as the beacon API encodes integers
+type Uint uint64
+
+func (u *Uint) UnmarshalJSON(data []byte) error {
+	s := ""
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid uint: %s", s)
+	}
+	*u = Uint(v)
+	return nil
+}
+
+type Checkpoint struct {
+	Epoch Uint   `json:"epoch"`
+	Root  string `json:"root"`
+}
+
+type FinalityCheckpoints struct {
+	PreviousJustified Checkpoint `json:"previous_justified"`
+	CurrentJustified  Checkpoint `json:"current_justified"`
+	Finalized         Checkpoint `json:"finalized"`
+}
+
+type Header struct {
+	Root   string `json:"root"`
+	Header struct {
+		Message struct {
+			Slot Uint `json:"slot"`
+		} `json:"message"`
+	} `json:"header"`
+}
+
+type Genesis struct {
+	// unix s
+	GenesisTime Uint `json:"genesis_time"`
+}
+
+// Beacon node REST API, e.g. http://localhost:5052
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:    strings.TrimSuffix(url, "/"),
+		client: &http.Client{Timeout: call_timeout},
+	}
+}
+
+// Decodes the data of GET path into result
+func (c *Client) get(ctx context.Context, path string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	http_res, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: %w", path, err))
+	}
+	defer http_res.Body.Close()
+	switch http_res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		// Unknown or pruned state
+		return status.NewErr(status.ErrCodeNotFound, fmt.Errorf("Not found: %s", path))
+	default:
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: status %d", path, http_res.StatusCode))
+	}
+
+	r := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	err = json.NewDecoder(http_res.Body).Decode(&r)
+	if err != nil {
+		return status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding %s: %w", path, err))
+	}
+	err = json.Unmarshal(r.Data, result)
+	if err != nil {
+		return status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding %s: %w", path, err))
+	}
+	return nil
+}
+
+func (c *Client) Genesis(ctx context.Context) (Genesis, error) {
+	genesis := Genesis{}
+	err := c.get(ctx, "/eth/v1/beacon/genesis", &genesis)
+	return genesis, err
+}
+
+// state_id: head, finalized, a slot, ...
+func (c *Client) FinalityCheckpoints(ctx context.Context, state_id string) (FinalityCheckpoints, error) {
+	checkpoints := FinalityCheckpoints{}
+	err := c.get(ctx, "/eth/v1/beacon/states/"+state_id+"/finality_checkpoints", &checkpoints)
+	return checkpoints, err
+}
+
+// block_id: head, finalized, a slot, ...
+func (c *Client) Header(ctx context.Context, block_id string) (Header, error) {
+	header := Header{}
+	err := c.get(ctx, "/eth/v1/beacon/headers/"+block_id, &header)
+	return header, err
+}
@@ -1,8 +1,12 @@
 package browser
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/headzoo/surf/browser"
@@ -41,12 +45,44 @@ func RandomUserAgent() string {
 	return user_agent_arr[int(b[0])%user_agent_arr_len]
 }
 
-func (b *Browser) Open(url string) {
+// Binds every request of an Open to its ctx, surf has no ctx of its own
+type ctxTransport struct {
+	ctx context.Context
+}
+
+func (t ctxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.DefaultTransport.RoundTrip(req.WithContext(t.ctx))
+}
+
+func (b *Browser) Open(ctx context.Context, url string) error {
 	// Randomize the user agent
 	b.b.SetUserAgent(RandomUserAgent())
+	b.b.SetTransport(ctxTransport{ctx})
 
 	fmt.Println("Opening", url)
-	b.b.Open(url)
+	open_err := b.b.Open(url)
+	if open_err != nil {
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error opening %s: %w", url, open_err))
+	}
+	// Rate limits & bot challenges
+	status_code := b.b.StatusCode()
+	if status_code != http.StatusOK {
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error opening %s: status %d", url, status_code))
+	}
+	if strings.Contains(b.b.Title(), "Just a moment") {
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error opening %s: bot challenge", url))
+	}
+	return nil
+}
+
+// Whether the page text contains text
+func (b *Browser) Contains(text string) bool {
+	return strings.Contains(b.b.Dom().Text(), text)
+}
+
+// Every match of selector, possibly empty
+func (b *Browser) Find(selector string) *goquery.Selection {
+	return b.b.Dom().Find(selector)
 }
 
 func (b *Browser) First(selector string) *goquery.Selection {
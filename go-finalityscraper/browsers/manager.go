@@ -6,6 +6,7 @@ import (
 	txs_browser "go-finalityscraper/browsers/txs"
 	"go-finalityscraper/common/chain"
 	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
 	"go-finalityscraper/latency_map"
 	"go-finalityscraper/server"
 	"sync"
@@ -29,6 +30,7 @@ func NewBrowserManager(
 	p chans.PChan,
 	l2_hash chans.L2HashChan,
 	root_l2_hash chans.RootL2HashChan,
+	bus *events.Bus,
 ) *BrowserManager {
 	bm := &BrowserManager{
 		wg:           &sync.WaitGroup{},
@@ -36,27 +38,27 @@ func NewBrowserManager(
 		l2_hash:      l2_hash,
 		root_l2_hash: root_l2_hash,
 
-		txs_b:  txs_browser.NewB(p, l2_hash),
-		l2_b:   l2_browser.NewB(l2_hash, root_l2_hash),
-		root_b: root_browser.NewB(root_l2_hash),
+		txs_b:  txs_browser.NewB(p, l2_hash, bus),
+		l2_b:   l2_browser.NewB(l2_hash, root_l2_hash, bus),
+		root_b: root_browser.NewB(root_l2_hash, bus),
 	}
 
 	return bm
 }
 
-func (bm *BrowserManager) StartScan(lm *latency_map.LatencyMap, from_chain_url chain.ChainUrl) {
+func (bm *BrowserManager) StartScan(lm *latency_map.LatencyMap, from_chain_id chain.ChainId, from_chain_url chain.ChainUrl) {
 	// Setup
 	bm.txs_b.SetModeScan(bm.wg, lm)
 	bm.l2_b.SetModeScan(bm.wg, lm)
 	bm.root_b.SetModeScan(bm.wg, lm)
 
 	// Start
-	go bm.txs_b.Main(from_chain_url)
+	go bm.txs_b.Main(from_chain_id, from_chain_url)
 	go bm.l2_b.Main()
 	go bm.root_b.Main()
 }
 
-func (bm *BrowserManager) StartServer(server *server.Server) {
+func (bm *BrowserManager) StartServer(server *server.Server, workers int) {
 	// Setup
 	bm.l2_b.SetModeServer(server)
 	bm.root_b.SetModeServer(server)
@@ -64,6 +66,10 @@ func (bm *BrowserManager) StartServer(server *server.Server) {
 	// Start
 	go bm.l2_b.Main()
 	go bm.root_b.Main()
+	for i := 1; i < workers; i++ {
+		go bm.l2_b.Fork().Main()
+		go bm.root_b.Fork().Main()
+	}
 }
 
 func (bm *BrowserManager) AddP(p int) {
@@ -2,22 +2,27 @@ package root_browser
 
 import (
 	"fmt"
-	"go-finalityscraper/browser"
+	"go-finalityscraper/common/chain"
 	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
+	"go-finalityscraper/common/status"
 	"go-finalityscraper/latency_map"
-	"go-finalityscraper/parse"
 	"go-finalityscraper/server"
-	"net/http"
+	"go-finalityscraper/source"
 	"strconv"
 	"sync"
-
-	"github.com/PuerkitoBio/goquery"
+	"time"
 )
 
+// Batches share one L1 tx, so it is only opened once per href
+const href_ttl = 10 * time.Minute
+
 type rootBProcessResult struct {
 	done chans.DoneChan
-	// root_end
-	result string
+	// Shared by every tx of the href, without settlement, see withSettlement
+	result chain.RootTx
+	// ModeServer
+	err error
 }
 type rootBHrefProcessMap struct {
 	*sync.Map
@@ -31,6 +36,14 @@ func (m *rootBHrefProcessMap) Get(href string) (*rootBProcessResult, bool) {
 	return nil, false
 }
 
+// Returns the process for href, or a new one if there is none (true)
+func (m *rootBHrefProcessMap) GetOrInit(href string) (*rootBProcessResult, bool) {
+	process, process_exists := m.LoadOrStore(href, &rootBProcessResult{
+		done: make(chans.DoneChan),
+	})
+	return process.(*rootBProcessResult), !process_exists
+}
+
 type B struct {
 	// ModeScan
 	wg *sync.WaitGroup
@@ -42,23 +55,33 @@ type B struct {
 	// chans
 	root_l2_hash chans.RootL2HashChan
 
+	bus *events.Bus
+
 	// Internal
-	*browser.Browser
-	Process          func(hash string, href string)
+	sources source.RootSources
+	hops    *source.Hops
+	// Shared with forks
+	finalities       *source.Finalities
+	settlers         *source.Settlers
+	Process          func(root_l2_hash chain.RootL2Hash)
 	href_process_map *rootBHrefProcessMap
 }
 
-func NewB(root_l2_hash chans.RootL2HashChan) *B {
+func NewB(root_l2_hash chans.RootL2HashChan, bus *events.Bus) *B {
 	return &B{
-		root_l2_hash: root_l2_hash,
-		Browser:      browser.NewBrowser(),
+		root_l2_hash:     root_l2_hash,
+		bus:              bus,
+		sources:          source.RootSources{},
+		hops:             source.NewHops(),
+		finalities:       source.NewFinalities(),
+		settlers:         source.NewSettlers(),
+		href_process_map: &rootBHrefProcessMap{&sync.Map{}},
 	}
 }
 
 func (b *B) SetModeScan(wg *sync.WaitGroup, lm *latency_map.LatencyMap) {
 	b.wg = wg
 	b.lm = lm
-	b.href_process_map = &rootBHrefProcessMap{&sync.Map{}}
 	b.Process = b.processForScan
 }
 
@@ -67,42 +90,71 @@ func (b *B) SetModeServer(sv *server.Server) {
 	b.Process = b.processForServer
 }
 
+// Another ModeServer B on the same chans & hrefs, with its own sources
+func (b *B) Fork() *B {
+	fork := *b
+	fork.sources = source.RootSources{}
+	fork.hops = source.NewHops()
+	fork.SetModeServer(b.sv)
+	return &fork
+}
+
 func (b *B) Main() {
 	for {
 		root_l2_hash := <-b.root_l2_hash
-		hash := root_l2_hash.Hash
-		href := root_l2_hash.Href
-		b.Process(hash, href)
+		b.Process(root_l2_hash)
 	}
 }
 
-func (b *B) processForScan(hash string, href string) {
+func (b *B) rootTx(root_l2_hash chain.RootL2Hash) (chain.RootTx, error) {
+	src, src_err := b.sources.Get(root_l2_hash.ChainId)
+	if src_err != nil {
+		return chain.RootTx{}, src_err
+	}
+	root_tx, root_tx_err := src.RootTx(root_l2_hash)
+	if root_tx_err != nil {
+		return root_tx, root_tx_err
+	}
+	b.attachFinality(root_l2_hash, &root_tx)
+	b.attachHops(root_l2_hash, &root_tx)
+	return root_tx, nil
+}
+
+// Sets the beacon checkpoint times of root_tx, if its root chain has a beacon node.
+// They are extra milestones, so failing to read them only leaves them unknown.
+func (b *B) attachFinality(root_l2_hash chain.RootL2Hash, root_tx *chain.RootTx) {
+	finality, finality_err := b.finalities.Get(root_l2_hash.ChainId)
+	if finality_err != nil || finality == nil {
+		return
+	}
+	justified, finalized, times_err := finality.BlockTimes(root_l2_hash.Ctx, root_tx.RootEnd)
+	if times_err != nil {
+		fmt.Println("Error reading beacon finality", root_l2_hash.Href+":", times_err)
+	}
+	root_tx.RootJustified = justified
+	root_tx.RootFinalized = finalized
+}
+
+func (b *B) processForScan(root_l2_hash chain.RootL2Hash) {
 	// Dec wg root_b
 	defer b.wg.Done()
+	hash := root_l2_hash.Hash
+	href := root_l2_hash.Href
 
 	process, process_exists := b.href_process_map.Get(href)
 	if !process_exists {
 		process = &rootBProcessResult{
-			done:   make(chans.DoneChan),
-			result: "",
+			done: make(chans.DoneChan),
 		}
 		b.href_process_map.Store(href, process)
 
-		b.Open(href)
-		ts_el := b.First("#showUtcLocalDate")
-
-		go func() {
-			defer close(process.done)
-			bp := b.newProcess()
-
-			root_end, root_end_err := bp.Process(ts_el)
-			if root_end_err != nil {
-				fmt.Println(root_end_err)
-				b.lm.RemoveHash(hash)
-				return
-			}
-			process.result = root_end
-		}()
+		root_tx, root_tx_err := b.rootTx(root_l2_hash)
+		if root_tx_err != nil {
+			fmt.Println(root_tx_err)
+			b.lm.RemoveHash(hash)
+		}
+		process.result = root_tx
+		close(process.done)
 	}
 
 	// Inc wg root_b process
@@ -111,56 +163,113 @@ func (b *B) processForScan(hash string, href string) {
 		// Dec wg root_b process
 		defer b.wg.Done()
 		<-(process.done)
-		b.lm.SetHashI(latency_map.Entry{
-			Hash: hash,
-			I:    latency_map.RootEnd,
-			V:    process.result,
-		})
+		root_tx := process.result
+		if root_tx.RootEnd != 0 {
+			b.publishTs(root_l2_hash, root_tx.RootEnd)
+			root_tx = b.withSettlement(root_l2_hash, root_tx)
+		}
+		milestones := root_tx.Milestones(root_l2_hash.ChainId, root_l2_hash.Start)
+		for i, milestone := range latency_map.Milestones {
+			v, v_exists := milestones[milestone]
+			if i == int(latency_map.Start) || !v_exists {
+				continue
+			}
+			b.lm.SetHashI(latency_map.Entry{
+				Hash: hash,
+				I:    latency_map.I(i),
+				V:    strconv.FormatInt(v, 10),
+			})
+		}
 	}()
 }
 
-func (b *B) processForServer(hash string, href string) {
-	b.Open(href)
-	ts_el := b.First("#showUtcLocalDate")
+func (b *B) processForServer(root_l2_hash chain.RootL2Hash) {
+	l2_hash := root_l2_hash.L2Hash
 
-	go func() {
-		bp := b.newProcess()
+	process, process_new := b.href_process_map.GetOrInit(root_l2_hash.Href)
+	if process_new {
+		b.openForServer(root_l2_hash, process)
+	}
 
-		root_end, root_end_err := bp.Process(ts_el)
-		if root_end_err != nil {
-			fmt.Println(root_end_err)
-			b.sv.SetChanRes(hash, server.NewHasErr(http.StatusNotFound, root_end_err))
+	go func() {
+		<-process.done
+		if process.err != nil {
+			if status.IsCtxErr(process.err) && l2_hash.Ctx.Err() == nil {
+				// Opened for a request that gave up, retry for this one
+				b.root_l2_hash <- root_l2_hash
+				return
+			}
+			fmt.Println(process.err)
+			b.bus.Publish(events.NewFailed(l2_hash, process.err))
+			b.sv.SetChanRes(l2_hash, server.NewHasErr(process.err))
 			return
 		}
 
-		b.sv.SetChanRes(hash, server.RootEndV{
-			HasCode: server.HasCode{
-				Code: http.StatusOK,
-			},
-			RootEndRes: server.RootEndRes{
-				RootEnd: root_end,
-			},
-		})
+		b.publishTs(root_l2_hash, process.result.RootEnd)
+		root_tx := b.withSettlement(root_l2_hash, process.result)
+		latency, latency_err := server.NewLatencyV(root_l2_hash, root_tx)
+		if latency_err != nil {
+			b.sv.SetChanRes(l2_hash, server.NewHasErr(latency_err))
+			return
+		}
+		b.sv.SetChanRes(l2_hash, latency)
 	}()
 }
 
-type bProcess struct {
-}
+func (b *B) openForServer(root_l2_hash chain.RootL2Hash, process *rootBProcessResult) {
+	href := root_l2_hash.Href
+	fail := func(err error) {
+		// Failed hrefs are retried by the next hash
+		b.href_process_map.Delete(href)
+		process.err = err
+		close(process.done)
+	}
 
-func (b *B) newProcess() *bProcess {
-	return &bProcess{}
+	root_tx, root_tx_err := b.rootTx(root_l2_hash)
+	if root_tx_err != nil {
+		fail(root_tx_err)
+		return
+	}
+
+	process.result = root_tx
+	close(process.done)
+	time.AfterFunc(href_ttl, func() {
+		b.href_process_map.Delete(href)
+	})
 }
 
-func (bp *bProcess) Process(ts_el *goquery.Selection) (string, error) {
-	if ts_el == nil {
-		return "", fmt.Errorf("Timestamp el not found")
+// root_tx of the href with the settlement of the L2 state covering the tx, if its
+// chain has one to track. It depends on the tx (e.g. its L2 block), not the href, so
+// it is read per tx. Like beacon finality, failing to read it only leaves it unknown.
+func (b *B) withSettlement(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) chain.RootTx {
+	settler, settler_err := b.settlers.Get(root_l2_hash.ChainId)
+	if settler_err != nil || settler == nil {
+		return root_tx
+	}
+	settlement, settlement_err := settler.Settle(root_l2_hash, root_tx)
+	if settlement_err != nil {
+		fmt.Println("Error reading settlement", root_l2_hash.Hash+":", settlement_err)
+		return root_tx
 	}
+	root_tx.Settlement = settlement
+	return root_tx
+}
 
-	root_end_time, root_end_err := parse.Date(ts_el.Text())
-	if root_end_err != nil {
-		return "", fmt.Errorf("Error parsing timestamp: %w", root_end_err)
+// Sets the hops down to the root chain of an L3 (or deeper) tx, whose root_end is
+// only on its parent. Like settlement, failing to read them only leaves them unknown.
+func (b *B) attachHops(root_l2_hash chain.RootL2Hash, root_tx *chain.RootTx) {
+	hops, hops_err := b.hops.Resolve(root_l2_hash)
+	if hops_err != nil {
+		fmt.Println("Error reading hops", root_l2_hash.Hash+":", hops_err)
+		return
 	}
+	root_tx.Hops = hops
+}
 
-	root_end := strconv.FormatInt(root_end_time.UnixMilli(), 10)
-	return root_end, nil
+// root_end, unix ms
+func (b *B) publishTs(root_l2_hash chain.RootL2Hash, root_end int64) {
+	e := events.NewEvent(events.EventL1TimestampParsed, root_l2_hash.L2Hash)
+	e.Href = root_l2_hash.Href
+	e.Timestamp = root_end
+	b.bus.Publish(e)
 }
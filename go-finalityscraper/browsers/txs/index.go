@@ -1,19 +1,22 @@
 package txs_browser
 
 import (
+	"context"
 	"fmt"
 	"go-finalityscraper/browser"
 	"go-finalityscraper/common/chain"
 	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
 	"go-finalityscraper/latency_map"
+	"go-finalityscraper/rpc"
+	"go-finalityscraper/settle"
+	"go-finalityscraper/source"
 	"strconv"
 	"sync"
 
 	"github.com/PuerkitoBio/goquery"
 )
 
-const txs_route string = "/txs?ps=10&p="
-
 type B struct {
 	// ModeScan
 	wg *sync.WaitGroup
@@ -23,15 +26,20 @@ type B struct {
 	p       chans.PChan
 	l2_hash chans.L2HashChan
 
+	bus *events.Bus
+
 	// Internal
 	*browser.Browser
-	process func(from_chain_url chain.ChainUrl)
+	process func(from_chain_id chain.ChainId, from_chain_url chain.ChainUrl)
+	// Of from_chain_id, set by Main
+	selectors chain.Selectors
 }
 
-func NewB(p chans.PChan, l2_hash chans.L2HashChan) *B {
+func NewB(p chans.PChan, l2_hash chans.L2HashChan, bus *events.Bus) *B {
 	return &B{
 		p:       p,
 		l2_hash: l2_hash,
+		bus:     bus,
 
 		Browser: browser.NewBrowser(),
 	}
@@ -43,15 +51,34 @@ func (b *B) SetModeScan(wg *sync.WaitGroup, lm *latency_map.LatencyMap) {
 	b.process = b.processForScan
 }
 
-func (b *B) Main(from_chain_url chain.ChainUrl) {
+func (b *B) Main(from_chain_id chain.ChainId, from_chain_url chain.ChainUrl) {
+	c, c_err := chain.Get(from_chain_id)
+	if c_err != nil {
+		panic(c_err)
+	}
+	// No explorer tx list to scrape, pages are blocks back from the L1 state
+	if c.Stack == chain.StackStarknet {
+		b.mainBlocks(c, from_chain_url)
+		return
+	}
+	if c.Routes.Txs == "" {
+		panic(fmt.Sprintf("No txs route on chain: %s", from_chain_id))
+	}
+	b.selectors = c.Selectors
 	for {
 		p := <-b.p
-		b.Open(string(from_chain_url) + txs_route + strconv.Itoa(p))
-		b.process(from_chain_url)
+		open_err := b.Open(context.Background(), string(from_chain_url)+c.Routes.Txs+strconv.Itoa(p))
+		if open_err != nil {
+			fmt.Println(open_err)
+			// Dec wg txs_b
+			b.wg.Done()
+			continue
+		}
+		b.process(from_chain_id, from_chain_url)
 	}
 }
 
-func (b *B) processForScan(from_chain_url chain.ChainUrl) {
+func (b *B) processForScan(from_chain_id chain.ChainId, from_chain_url chain.ChainUrl) {
 	// Dec wg txs_b
 	defer b.wg.Done()
 
@@ -77,29 +104,102 @@ func (b *B) processForScan(from_chain_url chain.ChainUrl) {
 					return
 				}
 
-				_, hash_exists := b.lm.Get(hash)
-				if hash_exists {
-					fmt.Println("Skipping:", hash+", already exists")
-					return
-				}
-
-				b.lm.InitHash(hash)
-				// Inc wg l2_b
-				b.wg.Add(1)
-				b.l2_hash <- chain.L2Hash{
-					ChainUrl: from_chain_url,
-					Hash:     hash,
-				}
+				b.found(from_chain_id, from_chain_url, hash)
 			}()
 		})
 	}()
 }
 
+// Starts the latency of hash unless it was scanned before
+func (b *B) found(from_chain_id chain.ChainId, from_chain_url chain.ChainUrl, hash string) {
+	_, hash_exists := b.lm.Get(hash)
+	if hash_exists {
+		fmt.Println("Skipping:", hash+", already exists")
+		return
+	}
+
+	b.lm.InitHash(hash)
+	l2_hash := chain.L2Hash{
+		Ctx:      context.Background(),
+		ChainId:  from_chain_id,
+		ChainUrl: from_chain_url,
+		Hash:     hash,
+	}
+	b.bus.Publish(events.NewEvent(events.EventTxFound, l2_hash))
+	// Inc wg l2_b
+	b.wg.Add(1)
+	b.l2_hash <- l2_hash
+}
+
+// Page p is the (p-1)th block before the last block ACCEPTED_ON_L1, read from the core contract
+// on RPC_URL_<parent>, on the L2 node RPC_URL_<from_chain_id>. Blocks past it have no batch yet.
+func (b *B) mainBlocks(c chain.Chain, from_chain_url chain.ChainUrl) {
+	from_chain_id := c.Id
+	if c.Finality.StarknetCore == nil {
+		panic(fmt.Sprintf("No core contract on chain: %s", from_chain_id))
+	}
+	rpc_url, rpc_url_err := source.EnvRpcUrl(from_chain_id)
+	if rpc_url_err != nil {
+		panic(rpc_url_err)
+	}
+	root_rpc_url, root_rpc_url_err := source.EnvRpcUrl(c.Parent)
+	if root_rpc_url_err != nil {
+		panic(root_rpc_url_err)
+	}
+	root_block_time, root_block_time_err := chain.MapChainIdAvgBlockTime(c.Parent)
+	if root_block_time_err != nil {
+		panic(root_block_time_err)
+	}
+	sn := rpc.NewStarknet(rpc.NewClient(rpc_url))
+	core := settle.NewStarknetCore(rpc.NewClient(root_rpc_url), root_block_time, *c.Finality.StarknetCore)
+	for {
+		p := <-b.p
+		block, block_err := pageBlock(sn, core, p)
+		if block_err != nil {
+			fmt.Println(block_err)
+			// Dec wg txs_b
+			b.wg.Done()
+			continue
+		}
+		for _, hash := range block.Transactions {
+			// Inc wg txs_b process tx
+			b.wg.Add(1)
+			go func(hash string) {
+				// Dec wg txs_b process tx
+				defer b.wg.Done()
+				b.found(from_chain_id, from_chain_url, hash)
+			}(hash)
+		}
+		// Dec wg txs_b
+		b.wg.Done()
+	}
+}
+
+func pageBlock(sn *rpc.Starknet, core *settle.StarknetCore, p int) (*rpc.StarknetBlock, error) {
+	ctx := context.Background()
+	accepted, accepted_err := core.StateBlock(ctx)
+	if accepted_err != nil {
+		return nil, accepted_err
+	}
+	if p < 1 || uint64(p-1) > accepted {
+		return nil, fmt.Errorf("No block for page: %d", p)
+	}
+	block, block_err := sn.GetBlockWithTxHashes(ctx, accepted-uint64(p-1))
+	if block_err != nil {
+		return nil, block_err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("Block not found: %d", accepted-uint64(p-1))
+	}
+	return block, nil
+}
+
 type bProcess struct {
+	selectors chain.Selectors
 }
 
 func (b *B) newProcess() *bProcess {
-	return &bProcess{}
+	return &bProcess{selectors: b.selectors}
 }
 
 func (bp *bProcess) processTBody(tbody_el *goquery.Selection, trFn func(int, *goquery.Selection)) {
@@ -110,14 +210,14 @@ func (bp *bProcess) processTBody(tbody_el *goquery.Selection, trFn func(int, *go
 }
 
 func (bp *bProcess) processTd(tr_el *goquery.Selection) (string, error) {
-	from_el := browser.First(tr_el, "td:nth-child(7)")
+	from_el := browser.First(tr_el, bp.selectors.TxsFrom)
 	if from_el == nil {
 		return "", fmt.Errorf("from address not found")
 	}
 
 	// Filter non-System txs
 	if from_el.Text() != "System Address" {
-		tx_el := tr_el.Find("td:nth-child(2) a")
+		tx_el := tr_el.Find(bp.selectors.TxsHash)
 		if tx_el == nil {
 			return "", fmt.Errorf("tx hash not found")
 		}
@@ -1,33 +1,290 @@
 package chain
 
-const (
-	L1Url = "https://etherscan.io"
+import (
+	"context"
+	"fmt"
+	"strings"
 )
 
 type ChainId string
 
-const (
-	ChainIdOptimism       ChainId = "10"
-	ChainIdOptimismGoerli ChainId = "420"
-	ChainIdArbitrum       ChainId = "42161"
-	ChainIdArbitrumGoerli ChainId = "421613"
-)
-
+// Explorer base url, e.g. https://optimistic.etherscan.io
 type ChainUrl string
 
-const (
-	ChainUrlOptimism       ChainUrl = "https://optimistic.etherscan.io"
-	ChainUrlOptimismGoerli ChainUrl = "https://goerli-optimism.etherscan.io"
-	ChainUrlArbitrum       ChainUrl = "https://arbiscan.io"
-	ChainUrlArbitrumGoerli ChainUrl = "https://goerli.arbiscan.io"
-)
+// First L2 block the batches build on, to map batch timestamps & message counts to blocks
+type Genesis struct {
+	Block uint64 `json:"block"`
+	// unix s
+	Time int64 `json:"time"`
+	// s, 0 if blocks are not produced at a fixed interval
+	BlockTime int64 `json:"block_time,omitempty"`
+}
+
+// L2 block at ts (unix s), for chains with a fixed BlockTime
+func (g Genesis) BlockAt(ts int64) uint64 {
+	if g.BlockTime == 0 || ts < g.Time {
+		return g.Block
+	}
+	return g.Block + uint64((ts-g.Time)/g.BlockTime)
+}
+
+// OP Stack fault proofs, L2 states are claimed in dispute games
+type DisputeGames struct {
+	// DisputeGameFactory on the root chain
+	Factory string `json:"factory"`
+	// Respected game type of the OptimismPortal
+	GameType uint32 `json:"game_type"`
+	// disputeGameFinalityDelaySeconds, from resolution until withdrawals can be finalized
+	AirGap Duration `json:"air_gap"`
+}
+
+// OP Stack L2OutputOracle, L2 states are claimed as outputs, final after the challenge period
+type OutputOracle struct {
+	// L2OutputOracle proxy on the root chain
+	Address string `json:"address"`
+}
+
+// Starknet core contract, its LogStateUpdate moves the L1 state to a proven L2 block
+type StarknetCore struct {
+	// Core contract proxy on the root chain
+	Address string `json:"address"`
+}
+
+// OP Stack accounts & contracts on the root chain, as in the superchain registry
+type OpStack struct {
+	// Batch sender, batch inbox txs of anyone else are not batches. Read from SystemConfig if empty
+	Batcher      string `json:"batcher,omitempty"`
+	SystemConfig string `json:"system_config,omitempty"`
+	// Batches are not decoded to their blocks, e.g. as the block time changed since genesis,
+	// so every batch inbox tx from the batcher may contain a block. Genesis is then not needed
+	NoBatchDecoding bool `json:"no_batch_decoding,omitempty"`
+}
+
+// Arbitrum rollup, L2 states are claimed in nodes, assertions since BoLD
+type Rollup struct {
+	// Rollup proxy on the root chain
+	Address string `json:"address"`
+	// L1 blocks from a node until it can be confirmed, assertions carry their own
+	ConfirmPeriodBlocks uint64 `json:"confirm_period_blocks"`
+}
 
 type L2Hash struct {
+	// Cancelled once the result is no longer wanted
+	Ctx      context.Context
+	ChainId  ChainId
 	ChainUrl ChainUrl
 	Hash     string
 }
 
+// Identifies a (chain, hash) pair, e.g. for sharing one scrape between requests
+func (l2_hash L2Hash) Key() string {
+	return string(l2_hash.ChainUrl) + "/tx/" + strings.ToLower(l2_hash.Hash)
+}
+
 type RootL2Hash struct {
-	Hash string
+	L2Hash
 	Href string
+	// L2 timestamp, unix ms
+	Start int64
+}
+
+// How a batch tx carries its data
+type PostingMode string
+
+const (
+	PostingModeCalldata PostingMode = "calldata"
+	// EIP-4844 blobs, type 3 txs
+	PostingModeBlob PostingMode = "blob"
+)
+
+// EIP-4844 tx type
+const TxTypeBlob = 3
+
+func PostingModeOf(tx_type uint64) PostingMode {
+	if tx_type == TxTypeBlob {
+		return PostingModeBlob
+	}
+	return PostingModeCalldata
+}
+
+// What root_b reads of the L1 batch tx
+type RootTx struct {
+	// L1 timestamp, unix ms
+	RootEnd int64
+	// L1 block of the batch tx, 0 if unknown
+	RootBlock   uint64
+	PostingMode PostingMode
+	// Blob versioned hashes, PostingModeBlob only
+	BlobHashes []string
+	// Beacon chain checkpoints of RootBlock, unix ms, 0 until known
+	RootJustified int64
+	RootFinalized int64
+	// Of the tx, not its batch: txs of a batch may be covered by different claims
+	Settlement Settlement
+	// L3s & deeper only, the batch tx on the parent down to the root chain, see Hop.
+	// Unlike Settlement, they only depend on the batch tx, see HopsOf for every hop.
+	Hops []Hop
+}
+
+// A tx batched into the parent of its chain. The batch tx is the tx of the next
+// hop, until the root chain.
+type Hop struct {
+	FromChain ChainId `json:"from_chain"`
+	ToChain   ChainId `json:"to_chain"`
+	Hash      string  `json:"hash"`
+	// Batch tx on to_chain, empty while not batched
+	Href string `json:"href"`
+	// unix ms, 0 until known
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	// start -> end, 0 while not batched
+	LatencyMs int64 `json:"latency_ms"`
+}
+
+// Every hop of root_l2_hash, its own batch first, nil if its parent is the root chain
+func (root_tx RootTx) HopsOf(root_l2_hash RootL2Hash) []Hop {
+	if len(root_tx.Hops) == 0 {
+		return nil
+	}
+	first := Hop{
+		FromChain: root_l2_hash.ChainId,
+		ToChain:   root_tx.Hops[0].FromChain,
+		Hash:      root_l2_hash.Hash,
+		Href:      root_l2_hash.Href,
+		Start:     root_l2_hash.Start,
+		End:       root_tx.RootEnd,
+		LatencyMs: root_tx.RootEnd - root_l2_hash.Start,
+	}
+	return append([]Hop{first}, root_tx.Hops...)
+}
+
+// unix ms the batch chain reached the root chain, 0 while a hop is pending
+func (root_tx RootTx) HopsEnd() int64 {
+	if len(root_tx.Hops) == 0 {
+		return 0
+	}
+	return root_tx.Hops[len(root_tx.Hops)-1].End
+}
+
+// L2 state claim on the root chain covering the tx, e.g. an OP dispute game,
+// the zkSync L1 batch committed, proven & executed, or the Polygon zkEVM batch
+// sequenced & verified
+type Settlement struct {
+	// Game address, L1 batch number, ..., empty until there is a claim
+	Id string `json:"id"`
+	// unix ms, 0 until reached
+	Proposed int64 `json:"proposed"`
+	Resolved int64 `json:"resolved"`
+	// Withdrawals of the tx can be finalized from then on
+	Finalized int64 `json:"finalized"`
+	// Polygon zkEVM only, when the trusted sequencer closed the batch
+	Trusted int64 `json:"trusted,omitempty"`
+	// L1 txs of the steps, empty if unknown or not a tx (e.g. OP games resolve in a call)
+	ProposedTx  string `json:"proposed_tx,omitempty"`
+	ResolvedTx  string `json:"resolved_tx,omitempty"`
+	FinalizedTx string `json:"finalized_tx,omitempty"`
+}
+
+// Known milestones of an L2 tx of chain_id starting at start, unix ms
+func (root_tx RootTx) Milestones(chain_id ChainId, start int64) map[Milestone]int64 {
+	milestones := map[Milestone]int64{
+		MilestoneL2Included: start,
+	}
+	if root_tx.RootEnd == 0 {
+		return milestones
+	}
+	milestones[MilestoneL1Posted] = root_tx.RootEnd
+	if root_tx.RootJustified != 0 {
+		milestones[MilestoneL1Justified] = root_tx.RootJustified
+	}
+	if root_tx.RootFinalized != 0 {
+		milestones[MilestoneL1Finalized] = root_tx.RootFinalized
+	}
+	challenge_period, challenge_period_err := MapChainIdChallengePeriod(chain_id)
+	if challenge_period_err == nil {
+		milestones[MilestoneChallengeEnd] = root_tx.RootEnd + challenge_period.Milliseconds()
+	}
+	settlement := root_tx.Settlement
+	if settlement.Proposed != 0 {
+		milestones[MilestoneStateProposed] = settlement.Proposed
+	}
+	if settlement.Resolved != 0 {
+		milestones[MilestoneStateResolved] = settlement.Resolved
+	}
+	if settlement.Finalized != 0 {
+		milestones[MilestoneStateFinalized] = settlement.Finalized
+	}
+	hops_end := root_tx.HopsEnd()
+	if hops_end != 0 {
+		milestones[MilestoneRootPosted] = hops_end
+	}
+	c, c_err := Get(chain_id)
+	if c_err == nil && c.Finality.Model == FinalityZkEvm {
+		if settlement.Trusted != 0 {
+			milestones[MilestoneZkTrusted] = settlement.Trusted
+		}
+		if settlement.Proposed != 0 {
+			milestones[MilestoneZkVirtual] = settlement.Proposed
+		}
+		if settlement.Finalized != 0 {
+			milestones[MilestoneZkConsolidated] = settlement.Finalized
+		}
+	}
+	return milestones
+}
+
+// Point a tx passes on its way from L2 to L1 finality
+type Milestone string
+
+const (
+	// L2 block timestamp
+	MilestoneL2Included Milestone = "l2_included"
+	// Batch tx included on L1
+	MilestoneL1Posted    Milestone = "l1_posted"
+	MilestoneL1Justified Milestone = "l1_justified"
+	MilestoneL1Finalized Milestone = "l1_finalized"
+	// L3s & deeper only, the batch tx of the last hop included on the root chain,
+	// e.g. Ethereum under an Orbit L3 on Arbitrum One
+	MilestoneRootPosted Milestone = "root_posted"
+	// Batch posted + challenge period
+	MilestoneChallengeEnd Milestone = "challenge_end"
+	// Settlement of the L2 state, e.g. an OP dispute game created, resolved,
+	// & past the air gap, when withdrawals can be finalized
+	MilestoneStateProposed  Milestone = "state_proposed"
+	MilestoneStateResolved  Milestone = "state_resolved"
+	MilestoneStateFinalized Milestone = "state_finalized"
+	// Polygon zkEVM batch closed by the trusted sequencer, sequenced on L1 (virtual)
+	// & verified on L1 (consolidated)
+	MilestoneZkTrusted      Milestone = "zk_trusted"
+	MilestoneZkVirtual      Milestone = "zk_virtual"
+	MilestoneZkConsolidated Milestone = "zk_consolidated"
+)
+
+// Every milestone, roughly in the order a tx passes them
+var Milestones = []Milestone{
+	MilestoneL2Included,
+	MilestoneZkTrusted,
+	MilestoneL1Posted,
+	MilestoneZkVirtual,
+	MilestoneRootPosted,
+	MilestoneL1Justified,
+	MilestoneL1Finalized,
+	MilestoneChallengeEnd,
+	MilestoneStateProposed,
+	MilestoneStateResolved,
+	MilestoneStateFinalized,
+	MilestoneZkConsolidated,
+}
+
+// Empty is MilestoneL1Posted, what latencies were always measured to
+func ParseMilestone(s string) (Milestone, error) {
+	if s == "" {
+		return MilestoneL1Posted, nil
+	}
+	for _, milestone := range Milestones {
+		if string(milestone) == s {
+			return milestone, nil
+		}
+	}
+	return "", fmt.Errorf("Unknown milestone: %s", s)
 }
@@ -1,18 +1,158 @@
 package chain
 
-import "fmt"
+import (
+	"fmt"
+	"go-finalityscraper/common/status"
+	"time"
+)
 
+// Registry lookups
+
+// Explorer of an L2, the chains txs are read from. Validates request chains,
+// so errors are unknown_chain or chain_deprecated.
 func MapChainIdUrl(chain_id ChainId) (ChainUrl, error) {
-	switch chain_id {
-	case ChainIdOptimism:
-		return ChainUrlOptimism, nil
-	case ChainIdOptimismGoerli:
-		return ChainUrlOptimismGoerli, nil
-	case ChainIdArbitrum:
-		return ChainUrlArbitrum, nil
-	case ChainIdArbitrumGoerli:
-		return ChainUrlArbitrumGoerli, nil
-	default:
-		return "", fmt.Errorf("Unknown chain id: %s", chain_id)
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return "", status.NewErr(status.ErrCodeUnknownChain, c_err)
+	}
+	if c.Deprecated != nil {
+		return "", status.NewErr(status.ErrCodeChainDeprecated, deprecatedErr(c))
+	}
+	return c.Url, nil
+}
+
+func deprecatedErr(c Chain) error {
+	err := fmt.Errorf("Chain %s (%s) is deprecated: %s", c.Id, c.Name, c.Deprecated.Reason)
+	replaced_by, replaced_by_err := Get(c.Deprecated.ReplacedBy)
+	if replaced_by_err != nil {
+		return err
+	}
+	return fmt.Errorf("%w, use %s (%s) instead", err, replaced_by.Id, replaced_by.Name)
+}
+
+// Explorer of the root chain of an L2, for hrefs of batch txs found elsewhere
+func MapChainIdRootUrl(chain_id ChainId) (ChainUrl, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return "", c_err
+	}
+	if c.RootUrl != "" {
+		return c.RootUrl, nil
+	}
+	root, root_err := Get(c.Parent)
+	if root_err != nil {
+		return "", root_err
+	}
+	return root.Url, nil
+}
+
+// Explorer API of any chain, L2 or root
+func MapChainIdApiUrl(chain_id ChainId) (string, error) {
+	c, c_err := Get(chain_id)
+	if c_err != nil {
+		return "", c_err
+	}
+	if c.ApiUrl == "" {
+		return "", fmt.Errorf("No explorer API on chain: %s", chain_id)
+	}
+	return c.ApiUrl, nil
+}
+
+func MapChainIdBatchInbox(chain_id ChainId) (string, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return "", c_err
+	}
+	if c.BatchInbox == "" {
+		return "", fmt.Errorf("No batch inbox on chain: %s", chain_id)
+	}
+	return c.BatchInbox, nil
+}
+
+func MapChainIdGenesis(chain_id ChainId) (Genesis, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return Genesis{}, c_err
+	}
+	if c.Genesis == nil {
+		return Genesis{}, fmt.Errorf("No genesis on chain: %s", chain_id)
+	}
+	return *c.Genesis, nil
+}
+
+// Only optimistic rollups
+func MapChainIdChallengePeriod(chain_id ChainId) (time.Duration, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return 0, c_err
+	}
+	if c.Finality.ChallengePeriod.Duration == 0 {
+		return 0, fmt.Errorf("No challenge period on chain: %s", chain_id)
+	}
+	return c.Finality.ChallengePeriod.Duration, nil
+}
+
+// Only OP Stack chains
+func MapChainIdOpStack(chain_id ChainId) (OpStack, error) {
+	c, c_err := Get(chain_id)
+	if c_err != nil || c.OpStack == nil {
+		return OpStack{}, fmt.Errorf("No OP Stack config on chain: %s", chain_id)
+	}
+	return *c.OpStack, nil
+}
+
+// Scans of chain_id cover a time span, e.g. 300 Ethereum blocks, 4,800 BSC blocks
+// or 14,400 Arbitrum blocks for an hour. Only parent chains.
+func MapChainIdAvgBlockTime(chain_id ChainId) (time.Duration, error) {
+	c, c_err := Get(chain_id)
+	if c_err != nil {
+		return 0, c_err
+	}
+	if c.AvgBlockTime.Duration == 0 {
+		return 0, fmt.Errorf("No avg block time on chain: %s", chain_id)
+	}
+	return c.AvgBlockTime.Duration, nil
+}
+
+// Blocks produced in d, rounded up
+func BlocksIn(d time.Duration, block_time time.Duration) uint64 {
+	return uint64((d + block_time - 1) / block_time)
+}
+
+// Chain the batches of chain_id are posted to
+func MapChainIdRoot(chain_id ChainId) (ChainId, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return "", c_err
+	}
+	return c.Parent, nil
+}
+
+// Chains a tx of an L2 is batched through, its parent first & the root chain (e.g.
+// Ethereum under an Orbit L3) last
+func MapChainIdParents(chain_id ChainId) ([]ChainId, error) {
+	c, c_err := mapL2(chain_id)
+	if c_err != nil {
+		return nil, c_err
+	}
+	parents := []ChainId{}
+	for c.IsL2() {
+		parents = append(parents, c.Parent)
+		c, c_err = Get(c.Parent)
+		if c_err != nil {
+			return nil, c_err
+		}
+	}
+	return parents, nil
+}
+
+func mapL2(chain_id ChainId) (Chain, error) {
+	c, c_err := Get(chain_id)
+	if c_err != nil {
+		return Chain{}, c_err
+	}
+	if !c.IsL2() {
+		return Chain{}, fmt.Errorf("Not an L2: %s", chain_id)
 	}
+	return c, nil
 }
@@ -0,0 +1,303 @@
+package chain
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Default registry, CHAINS_PATH replaces it
+//
+//go:embed chains.json
+var default_chains []byte
+
+// How batches of an L2 are encoded
+type Stack string
+
+const (
+	// Optimism, Base & other OP Stack chains, see OpStack
+	StackOpStack  Stack = "op_stack"
+	StackArbitrum Stack = "arbitrum"
+	// Validity rollup, batches are read through zks_* on the L2 node
+	StackZkSync Stack = "zksync"
+	// Validity rollup, blocks are settled by core contract state updates
+	StackStarknet Stack = "starknet"
+	// Validity rollup, batches are read through zkevm_* on the L2 node
+	StackPolygonZkEvm Stack = "polygon_zkevm"
+)
+
+// How the finality of a chain is tracked past its batches
+type FinalityModel string
+
+const (
+	// Root chains, Casper FFG checkpoints, see BEACON_URL_<chain_id>
+	FinalityBeacon FinalityModel = "beacon"
+	// Root chains without a beacon chain, e.g. BSC fast finality, not tracked
+	FinalityParlia FinalityModel = "parlia"
+	// Batch posted + challenge period
+	FinalityChallengePeriod FinalityModel = "challenge_period"
+	// + OP Stack dispute games
+	FinalityDisputeGames FinalityModel = "dispute_games"
+	// + OP Stack outputs, before fault proofs
+	FinalityOutputOracle FinalityModel = "output_oracle"
+	// + Arbitrum rollup nodes & assertions
+	FinalityRollup FinalityModel = "rollup"
+	// zkSync Era L1 batches committed, proven & executed, no challenge period
+	FinalityZkSync FinalityModel = "zksync"
+	// Starknet core contract state updates, posted & proven at once
+	FinalityStarknet FinalityModel = "starknet"
+	// Polygon zkEVM batches trusted, virtual (sequenced) & consolidated (verified)
+	FinalityZkEvm FinalityModel = "zkevm"
+)
+
+type Finality struct {
+	Model FinalityModel `json:"model"`
+	// Time after a batch is posted until it can no longer be challenged, for optimistic rollups
+	ChallengePeriod Duration      `json:"challenge_period"`
+	DisputeGames    *DisputeGames `json:"dispute_games,omitempty"`
+	OutputOracle    *OutputOracle `json:"output_oracle,omitempty"`
+	Rollup          *Rollup       `json:"rollup,omitempty"`
+	StarknetCore    *StarknetCore `json:"starknet_core,omitempty"`
+}
+
+// Explorer routes, appended to the explorer url
+type Routes struct {
+	// + tx hash
+	Tx string `json:"tx"`
+	// + page, L2s only
+	Txs string `json:"txs,omitempty"`
+}
+
+// CSS selectors of the explorer pages, empty if the html source cannot read them
+type Selectors struct {
+	// Tx page
+	Timestamp string `json:"timestamp"`
+	// Tx page link to the batch tx, L2s only
+	BatchTx string `json:"batch_tx,omitempty"`
+	// Txs page, per tr
+	TxsHash string `json:"txs_hash,omitempty"`
+	TxsFrom string `json:"txs_from,omitempty"`
+}
+
+type Chain struct {
+	Id   ChainId `json:"id"`
+	Name string  `json:"name"`
+	// Explorer
+	Url ChainUrl `json:"url"`
+	// Etherscan-compatible explorer API
+	ApiUrl string `json:"api_url,omitempty"`
+	// Chain the batches are posted to, empty for root chains
+	Parent ChainId `json:"parent,omitempty"`
+	// Average time between blocks, to size block scans of the chain as a parent,
+	// required for parents
+	AvgBlockTime Duration `json:"avg_block_time"`
+	// Explorer of Parent, for hrefs of batch txs found elsewhere
+	RootUrl   ChainUrl  `json:"root_url,omitempty"`
+	Stack     Stack     `json:"stack,omitempty"`
+	Routes    Routes    `json:"routes"`
+	Selectors Selectors `json:"selectors"`
+	// Where batches are posted to on Parent, a batch inbox EOA or SequencerInbox
+	BatchInbox string   `json:"batch_inbox,omitempty"`
+	OpStack    *OpStack `json:"op_stack,omitempty"`
+	Genesis    *Genesis `json:"genesis,omitempty"`
+	Finality   Finality `json:"finality"`
+	// nil while the chain is supported
+	Deprecated *Deprecation `json:"deprecated,omitempty"`
+}
+
+// Retired networks stay listed, so their requests fail clearly
+type Deprecation struct {
+	Reason string `json:"reason"`
+	// Chain to use instead
+	ReplacedBy ChainId `json:"replaced_by,omitempty"`
+}
+
+// Whether batches of the chain are posted elsewhere
+func (c Chain) IsL2() bool {
+	return c.Parent != ""
+}
+
+// time.Duration as a string, e.g. "168h"
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	s := ""
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("Invalid duration: %s", s)
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Chains in file order
+type Registry struct {
+	chains []Chain
+	by_id  map[ChainId]Chain
+}
+
+func ParseRegistry(data []byte) (*Registry, error) {
+	chains := []Chain{}
+	err := json.Unmarshal(data, &chains)
+	if err != nil {
+		return nil, fmt.Errorf("Error parsing chains: %w", err)
+	}
+	r := &Registry{
+		chains: chains,
+		by_id:  map[ChainId]Chain{},
+	}
+	for _, c := range chains {
+		if c.Id == "" || c.Url == "" {
+			return nil, fmt.Errorf("Chain without id or url: %s", c.Name)
+		}
+		_, c_exists := r.by_id[c.Id]
+		if c_exists {
+			return nil, fmt.Errorf("Duplicate chain id: %s", c.Id)
+		}
+		r.by_id[c.Id] = c
+	}
+	for _, c := range chains {
+		err := r.validate(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+func (r *Registry) validate(c Chain) error {
+	if c.Routes.Tx == "" {
+		return fmt.Errorf("Chain %s: tx route required", c.Id)
+	}
+	if c.IsL2() {
+		_, parent_exists := r.by_id[c.Parent]
+		if !parent_exists {
+			return fmt.Errorf("Chain %s: unknown parent %s", c.Id, c.Parent)
+		}
+		// Block scans on the parent are sized by it
+		if r.by_id[c.Parent].AvgBlockTime.Duration == 0 {
+			return fmt.Errorf("Chain %s: parent %s needs avg_block_time", c.Id, c.Parent)
+		}
+		// Parents of L3s are L2s, each chain must still end at a root chain
+		parent := r.by_id[c.Parent]
+		for hops := 1; parent.IsL2(); hops++ {
+			if hops > len(r.by_id) {
+				return fmt.Errorf("Chain %s: parents loop", c.Id)
+			}
+			parent = r.by_id[parent.Parent]
+		}
+		switch c.Stack {
+		case StackOpStack:
+			err := validateOpStack(c)
+			if err != nil {
+				return err
+			}
+		case StackArbitrum, StackZkSync, StackStarknet, StackPolygonZkEvm:
+		default:
+			return fmt.Errorf("Chain %s: unknown stack %s", c.Id, c.Stack)
+		}
+	}
+	if c.Deprecated != nil && c.Deprecated.ReplacedBy != "" {
+		_, replaced_by_exists := r.by_id[c.Deprecated.ReplacedBy]
+		if !replaced_by_exists {
+			return fmt.Errorf("Chain %s: unknown replaced_by %s", c.Id, c.Deprecated.ReplacedBy)
+		}
+	}
+	switch c.Finality.Model {
+	case FinalityBeacon, FinalityParlia, FinalityChallengePeriod, FinalityZkSync, FinalityZkEvm:
+	case FinalityDisputeGames:
+		if c.Finality.DisputeGames == nil {
+			return fmt.Errorf("Chain %s: dispute_games required", c.Id)
+		}
+	case FinalityOutputOracle:
+		if c.Finality.OutputOracle == nil || c.Finality.ChallengePeriod.Duration == 0 {
+			return fmt.Errorf("Chain %s: output_oracle & challenge_period required", c.Id)
+		}
+	case FinalityRollup:
+		if c.Finality.Rollup == nil {
+			return fmt.Errorf("Chain %s: rollup required", c.Id)
+		}
+	case FinalityStarknet:
+		if c.Finality.StarknetCore == nil {
+			return fmt.Errorf("Chain %s: starknet_core required", c.Id)
+		}
+	default:
+		return fmt.Errorf("Chain %s: unknown finality model %s", c.Id, c.Finality.Model)
+	}
+	return nil
+}
+
+// The batcher check needs the batcher or SystemConfig, batch.Decode the genesis
+// & its block time. Retired chains are no longer read
+func validateOpStack(c Chain) error {
+	if c.Deprecated != nil {
+		return nil
+	}
+	if c.OpStack == nil || (c.OpStack.Batcher == "" && c.OpStack.SystemConfig == "") {
+		return fmt.Errorf("Chain %s: op_stack batcher or system_config required", c.Id)
+	}
+	if !c.OpStack.NoBatchDecoding && (c.Genesis == nil || c.Genesis.BlockTime == 0) {
+		return fmt.Errorf("Chain %s: genesis with block_time required, unless op_stack.no_batch_decoding", c.Id)
+	}
+	return nil
+}
+
+func (r *Registry) Chains() []Chain {
+	return r.chains
+}
+
+func (r *Registry) Get(chain_id ChainId) (Chain, error) {
+	c, c_exists := r.by_id[chain_id]
+	if !c_exists {
+		return Chain{}, fmt.Errorf("Unknown chain id: %s", chain_id)
+	}
+	return c, nil
+}
+
+// Read by every lookup, only replaced at startup
+var registry = mustParseRegistry(default_chains)
+
+func mustParseRegistry(data []byte) *Registry {
+	r, err := ParseRegistry(data)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// .env CHAINS_PATH, the embedded chains.json if empty
+func LoadRegistry(path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Error reading chains: %w", err)
+	}
+	r, err := ParseRegistry(data)
+	if err != nil {
+		return err
+	}
+	registry = r
+	return nil
+}
+
+func Chains() []Chain {
+	return registry.Chains()
+}
+
+func Get(chain_id ChainId) (Chain, error) {
+	return registry.Get(chain_id)
+}
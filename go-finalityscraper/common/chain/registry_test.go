@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"strings"
+	"testing"
+)
+
+// Every OP Stack chain of chains.json has what the batcher check & batch decoding need
+func TestDefaultRegistryOpStack(t *testing.T) {
+	r, err := ParseRegistry(default_chains)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range r.Chains() {
+		if c.Stack != StackOpStack || c.Deprecated != nil {
+			continue
+		}
+		t.Run(string(c.Id), func(t *testing.T) {
+			if c.OpStack == nil || (c.OpStack.Batcher == "" && c.OpStack.SystemConfig == "") {
+				t.Fatalf("got op_stack %+v, want a batcher or system_config", c.OpStack)
+			}
+			if c.OpStack.NoBatchDecoding {
+				return
+			}
+			if c.Genesis == nil || c.Genesis.BlockTime == 0 || c.Genesis.Time == 0 {
+				t.Fatalf("got genesis %+v, want its time & block_time", c.Genesis)
+			}
+		})
+	}
+}
+
+func TestParseRegistryOpStack(t *testing.T) {
+	root := `{ "id": "1", "url": "https://etherscan.io", "avg_block_time": "12s", "routes": { "tx": "/tx/" }, "finality": { "model": "beacon" } }`
+	l2 := func(fields string) string {
+		return `[` + root + `, { "id": "10", "url": "https://optimistic.etherscan.io", "parent": "1", "stack": "op_stack",
+			"routes": { "tx": "/tx/" }, "finality": { "model": "challenge_period" }` + fields + ` }]`
+	}
+	genesis := `, "genesis": { "block": 0, "time": 1686068903, "block_time": 2 }`
+
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "batcher & genesis", data: l2(`, "op_stack": { "batcher": "0xb" }` + genesis)},
+		{name: "no batch decoding", data: l2(`, "op_stack": { "system_config": "0xc", "no_batch_decoding": true }`)},
+		{name: "deprecated", data: l2(`, "deprecated": { "reason": "retired" }`)},
+		{name: "no op_stack", data: l2(genesis), err: "batcher or system_config"},
+		{name: "no batcher", data: l2(`, "op_stack": {}` + genesis), err: "batcher or system_config"},
+		{name: "no genesis", data: l2(`, "op_stack": { "batcher": "0xb" }`), err: "genesis"},
+		{name: "no block time", data: l2(`, "op_stack": { "batcher": "0xb" }, "genesis": { "block": 0, "time": 1686068903 }`), err: "genesis"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRegistry([]byte(tt.data))
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("got error %v, want %s", err, tt.err)
+			}
+		})
+	}
+}
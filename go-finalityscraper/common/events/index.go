@@ -0,0 +1,109 @@
+package events
+
+import (
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Per subscriber, events to slow subscribers are dropped
+const sub_buffer = 64
+
+type EventType string
+
+const (
+	// txs_b found the hash on a txs page
+	EventTxFound EventType = "tx_found"
+	// l2_b opened the L2 explorer page of the tx
+	EventL2PageFetched EventType = "l2_page_fetched"
+	// l2_b found the L1 tx batching the tx
+	EventBatchHrefFound EventType = "batch_href_found"
+	// root_b parsed the timestamp of the L1 tx
+	EventL1TimestampParsed EventType = "l1_timestamp_parsed"
+	EventFailed            EventType = "failed"
+)
+
+type Event struct {
+	Type      EventType     `json:"type"`
+	FromChain chain.ChainId `json:"from_chain"`
+	Hash      string        `json:"hash"`
+	// EventBatchHrefFound, EventL1TimestampParsed
+	Href string `json:"href,omitempty"`
+	// L2 ts for EventBatchHrefFound, L1 ts for EventL1TimestampParsed, unix ms
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// EventFailed
+	Err  string         `json:"err,omitempty"`
+	Code status.ErrCode `json:"code,omitempty"`
+	// unix ms
+	Time int64 `json:"time"`
+}
+
+func NewEvent(event_type EventType, l2_hash chain.L2Hash) Event {
+	return Event{
+		Type:      event_type,
+		FromChain: l2_hash.ChainId,
+		Hash:      l2_hash.Hash,
+		Time:      time.Now().UnixMilli(),
+	}
+}
+
+func NewFailed(l2_hash chain.L2Hash, err error) Event {
+	e := NewEvent(EventFailed, l2_hash)
+	e.Err = err.Error()
+	e.Code = status.CodeOf(err)
+	return e
+}
+
+// Whether e is about from_chain_id & hash, empty matches any
+func (e Event) Matches(from_chain_id chain.ChainId, hash string) bool {
+	if from_chain_id != "" && e.FromChain != from_chain_id {
+		return false
+	}
+	return hash == "" || strings.EqualFold(e.Hash, hash)
+}
+
+type EventChan chan Event
+
+// Fans events from the browsers out to every subscriber
+type Bus struct {
+	mu   *sync.RWMutex
+	subs map[EventChan]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		mu:   &sync.RWMutex{},
+		subs: map[EventChan]struct{}{},
+	}
+}
+
+// Never blocks the browsers
+func (bus *Bus) Publish(e Event) {
+	bus.mu.RLock()
+	defer bus.mu.RUnlock()
+	for sub := range bus.subs {
+		select {
+		case sub <- e:
+		default:
+		}
+	}
+}
+
+// Returns the subscription & a func ending it
+func (bus *Bus) Subscribe() (EventChan, func()) {
+	sub := make(EventChan, sub_buffer)
+	bus.mu.Lock()
+	bus.subs[sub] = struct{}{}
+	bus.mu.Unlock()
+
+	once := &sync.Once{}
+	return sub, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			delete(bus.subs, sub)
+			bus.mu.Unlock()
+		})
+	}
+}
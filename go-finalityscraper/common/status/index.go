@@ -0,0 +1,106 @@
+package status
+
+import (
+	"context"
+	"errors"
+)
+
+// Where a tx is on its way to L1
+type TxStatus string
+
+const (
+	// On L2, not batched yet
+	TxStatusPendingL2 TxStatus = "pending_l2"
+	// Batch tx known, not included on L1 yet
+	TxStatusBatched    TxStatus = "batched"
+	TxStatusL1Included TxStatus = "l1_included"
+	// Explorer pages could not be read
+	TxStatusScrapeFailed TxStatus = "scrape_failed"
+)
+
+// Stable, machine-readable error codes
+type ErrCode string
+
+const (
+	ErrCodeUnknownChain ErrCode = "unknown_chain"
+	// Retired network, see the chain registry
+	ErrCodeChainDeprecated ErrCode = "chain_deprecated"
+	ErrCodeInvalidRequest  ErrCode = "invalid_request"
+	ErrCodeNotFound        ErrCode = "not_found"
+	ErrCodeTxNotFound      ErrCode = "tx_not_found"
+	ErrCodeNotBatched      ErrCode = "not_batched"
+	ErrCodeL1Unconfirmed   ErrCode = "l1_unconfirmed"
+	// On L1, but not at the requested milestone yet
+	ErrCodeMilestonePending ErrCode = "milestone_pending"
+	// Explorer unreachable, rate-limited or serving a bot challenge
+	ErrCodeExplorerUnavailable ErrCode = "explorer_unavailable"
+	// Explorer page read, but not what the selectors expect, or a node or API
+	// response that cannot be decoded. Not retried, it would read the same.
+	ErrCodeScrapeFailed ErrCode = "scrape_failed"
+	ErrCodeTimeout      ErrCode = "timeout"
+	// Route turned off in .env, e.g. /watch without WATCH_SECRET
+	ErrCodeDisabled ErrCode = "disabled"
+	ErrCodeUnknown  ErrCode = "unknown"
+)
+
+// Whether the same request may succeed later
+func (code ErrCode) Retry() bool {
+	switch code {
+	case ErrCodeNotBatched, ErrCodeL1Unconfirmed, ErrCodeMilestonePending, ErrCodeExplorerUnavailable, ErrCodeTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Tx status implied by code, empty if it says nothing about the tx
+func (code ErrCode) TxStatus() TxStatus {
+	switch code {
+	case ErrCodeNotBatched:
+		return TxStatusPendingL2
+	case ErrCodeL1Unconfirmed:
+		return TxStatusBatched
+	case ErrCodeMilestonePending:
+		return TxStatusL1Included
+	case ErrCodeExplorerUnavailable, ErrCodeScrapeFailed:
+		return TxStatusScrapeFailed
+	default:
+		return ""
+	}
+}
+
+type Err struct {
+	Code ErrCode
+	Err  error
+}
+
+func NewErr(code ErrCode, err error) *Err {
+	return &Err{
+		Code: code,
+		Err:  err,
+	}
+}
+
+func (e *Err) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Err) Unwrap() error {
+	return e.Err
+}
+
+func IsCtxErr(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// Code of err, timeout for ctx errors, unknown if err was never classified
+func CodeOf(err error) ErrCode {
+	if IsCtxErr(err) {
+		return ErrCodeTimeout
+	}
+	var status_err *Err
+	if errors.As(err, &status_err) {
+		return status_err.Code
+	}
+	return ErrCodeUnknown
+}
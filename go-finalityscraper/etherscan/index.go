@@ -0,0 +1,170 @@
+package etherscan
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const call_timeout = 30 * time.Second
+
+// Etherscan-compatible REST API, e.g. https://api.etherscan.io/api
+type Client struct {
+	url    string
+	key    string
+	client *http.Client
+}
+
+func NewClient(api_url string, api_key string) *Client {
+	return &Client{
+		url:    api_url,
+		key:    api_key,
+		client: &http.Client{Timeout: call_timeout},
+	}
+}
+
+// module=account & co. answer with a status, module=proxy like JSON-RPC
+type res struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message"`
+	Result  json.RawMessage `json:"result"`
+	Error   *rpc.Error      `json:"error"`
+}
+
+func (c *Client) get(ctx context.Context, query url.Values) (res, error) {
+	if c.key != "" {
+		query.Set("apikey", c.key)
+	}
+	action := query.Get("module") + "." + query.Get("action")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"?"+query.Encode(), nil)
+	if err != nil {
+		return res{}, err
+	}
+	http_res, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return res{}, ctx.Err()
+		}
+		return res{}, status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: %w", action, err))
+	}
+	defer http_res.Body.Close()
+	if http_res.StatusCode != http.StatusOK {
+		return res{}, status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: status %d", action, http_res.StatusCode))
+	}
+
+	r := res{}
+	err = json.NewDecoder(http_res.Body).Decode(&r)
+	if err != nil {
+		return res{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding %s: %w", action, err))
+	}
+	// JSON-RPC errors of module=proxy
+	if r.Error != nil {
+		return res{}, status.NewErr(status.ErrCodeExplorerUnavailable, r.Error)
+	}
+	// Rate limits & invalid keys, the reason is in result
+	if r.Status == "0" && r.Message != "No transactions found" && r.Message != "No records found" {
+		reason := ""
+		json.Unmarshal(r.Result, &reason)
+		return res{}, status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: %s %s", action, r.Message, reason))
+	}
+	return r, nil
+}
+
+// rpc.Caller through module=proxy, for the eth_* methods it supports
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	query := url.Values{}
+	query.Set("module", "proxy")
+	query.Set("action", method)
+
+	switch method {
+	case "eth_blockNumber":
+	case "eth_getTransactionByHash":
+		query.Set("txhash", fmt.Sprint(params[0]))
+	case "eth_getBlockByNumber":
+		query.Set("tag", fmt.Sprint(params[0]))
+		query.Set("boolean", fmt.Sprint(params[1]))
+	case "eth_call":
+		msg, msg_ok := params[0].(rpc.CallMsg)
+		if !msg_ok {
+			return fmt.Errorf("Invalid eth_call params")
+		}
+		query.Set("to", msg.To)
+		query.Set("data", msg.Data)
+		query.Set("tag", fmt.Sprint(params[1]))
+	default:
+		return fmt.Errorf("Unsupported proxy method: %s", method)
+	}
+
+	r, err := c.get(ctx, query)
+	if err != nil {
+		return err
+	}
+	return rpc.Decode(r.Result, result)
+}
+
+// A module=account txlist entry
+type Tx struct {
+	Hash        string `json:"hash"`
+	BlockNumber string `json:"blockNumber"`
+	// unix s
+	TimeStamp string `json:"timeStamp"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Input     string `json:"input"`
+	IsError   string `json:"isError"`
+}
+
+// Txs of address within [start_block, end_block], oldest first, at most limit
+func (c *Client) TxList(ctx context.Context, address string, start_block uint64, end_block uint64, limit int) ([]Tx, error) {
+	query := url.Values{}
+	query.Set("module", "account")
+	query.Set("action", "txlist")
+	query.Set("address", address)
+	query.Set("startblock", strconv.FormatUint(start_block, 10))
+	query.Set("endblock", strconv.FormatUint(end_block, 10))
+	query.Set("page", "1")
+	query.Set("offset", strconv.Itoa(limit))
+	query.Set("sort", "asc")
+
+	r, err := c.get(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	txs := []Tx{}
+	err = rpc.Decode(r.Result, &txs)
+	if err != nil {
+		return nil, fmt.Errorf("Error decoding txlist: %w", err)
+	}
+	return txs, nil
+}
+
+// Last block at or before ts (unix ms), which exists even for recent ts
+func (c *Client) BlockBeforeTime(ctx context.Context, ts int64) (uint64, error) {
+	query := url.Values{}
+	query.Set("module", "block")
+	query.Set("action", "getblocknobytime")
+	query.Set("timestamp", strconv.FormatInt(ts/1000, 10))
+	query.Set("closest", "before")
+
+	r, err := c.get(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	number_str := ""
+	err = rpc.Decode(r.Result, &number_str)
+	if err != nil {
+		return 0, fmt.Errorf("Error decoding getblocknobytime: %w", err)
+	}
+	number, number_err := strconv.ParseUint(number_str, 10, 64)
+	if number_err != nil {
+		return 0, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding getblocknobytime: %w", number_err))
+	}
+	return number, nil
+}
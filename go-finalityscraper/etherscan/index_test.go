@@ -0,0 +1,160 @@
+package etherscan
+
+import (
+	"context"
+	"errors"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Local stand-in for the API, answering every query with body
+func newApiServer(t *testing.T, code int, body string) *Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("apikey") != "key" {
+			t.Errorf("Missing apikey: %s", r.URL.RawQuery)
+		}
+		w.WriteHeader(code)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL, "key")
+}
+
+func TestTxList(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		body      string
+		want      []Tx
+		want_code status.ErrCode
+	}{
+		{
+			name: "ok",
+			code: http.StatusOK,
+			body: `{"status":"1","message":"OK","result":[{"hash":"0xbatch","blockNumber":"3","timeStamp":"1024","from":"0x1","to":"0x2","input":"0x00","isError":"0"}]}`,
+			want: []Tx{{Hash: "0xbatch", BlockNumber: "3", TimeStamp: "1024", From: "0x1", To: "0x2", Input: "0x00", IsError: "0"}},
+		},
+		{
+			name: "no transactions",
+			code: http.StatusOK,
+			body: `{"status":"0","message":"No transactions found","result":[]}`,
+			want: []Tx{},
+		},
+		{
+			name:      "rate limit",
+			code:      http.StatusOK,
+			body:      `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`,
+			want_code: status.ErrCodeExplorerUnavailable,
+		},
+		{
+			name:      "invalid key",
+			code:      http.StatusOK,
+			body:      `{"status":"0","message":"NOTOK","result":"Invalid API Key"}`,
+			want_code: status.ErrCodeExplorerUnavailable,
+		},
+		{
+			name:      "http error",
+			code:      http.StatusBadGateway,
+			body:      `<html>Bad Gateway</html>`,
+			want_code: status.ErrCodeExplorerUnavailable,
+		},
+		{
+			name:      "malformed body",
+			code:      http.StatusOK,
+			body:      `<html>Just a moment...</html>`,
+			want_code: status.ErrCodeScrapeFailed,
+		},
+		{
+			name:      "malformed result",
+			code:      http.StatusOK,
+			body:      `{"status":"1","message":"OK","result":{"hash":"0xbatch"}}`,
+			want_code: status.ErrCodeScrapeFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newApiServer(t, tt.code, tt.body)
+
+			got, err := c.TxList(context.Background(), "0x2", 0, 300, 100)
+			if tt.want_code != "" {
+				if status.CodeOf(err) != tt.want_code {
+					t.Fatalf("got error %v, want %s", err, tt.want_code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %+v, want %+v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestBlockBeforeTime(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		want      uint64
+		want_code status.ErrCode
+	}{
+		{name: "ok", body: `{"status":"1","message":"OK","result":"19000000"}`, want: 19_000_000},
+		{name: "rate limit", body: `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`, want_code: status.ErrCodeExplorerUnavailable},
+		{name: "not a number", body: `{"status":"1","message":"OK","result":"Error! No closest block found"}`, want_code: status.ErrCodeScrapeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newApiServer(t, http.StatusOK, tt.body)
+
+			got, err := c.BlockBeforeTime(context.Background(), 1_700_000_000_000)
+			if tt.want_code != "" {
+				if status.CodeOf(err) != tt.want_code {
+					t.Fatalf("got error %v, want %s", err, tt.want_code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCall(t *testing.T) {
+	c := newApiServer(t, http.StatusOK, `{"jsonrpc":"2.0","id":1,"result":"0x10"}`)
+	got := rpc.Quantity(0)
+	err := c.Call(context.Background(), &got, "eth_blockNumber")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 16 {
+		t.Fatalf("got %d, want 16", got)
+	}
+
+	c = newApiServer(t, http.StatusOK, `{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"invalid argument"}}`)
+	err = c.Call(context.Background(), &got, "eth_getTransactionByHash", "0xa")
+	var rpc_err *rpc.Error
+	if !errors.As(err, &rpc_err) || rpc_err.Code != -32000 {
+		t.Fatalf("got error %v, want the RPC error", err)
+	}
+	if status.CodeOf(err) != status.ErrCodeExplorerUnavailable {
+		t.Fatalf("got error %v, want %s", err, status.ErrCodeExplorerUnavailable)
+	}
+
+	err = c.Call(context.Background(), &got, "eth_getLogs")
+	if err == nil {
+		t.Fatal("got no error for an unsupported method")
+	}
+}
@@ -5,16 +5,20 @@ import (
 	browser_manager "go-finalityscraper/browsers"
 	"go-finalityscraper/common/chain"
 	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
 	"go-finalityscraper/common/modes"
 	"go-finalityscraper/latency_map"
 	"go-finalityscraper/parse"
 	"go-finalityscraper/server"
+	"go-finalityscraper/store"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
 const csv_path = "data.csv"
+const default_store_path = "store.db"
 
 func main() {
 	// Load env
@@ -22,14 +26,21 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
+	err = chain.LoadRegistry(os.Getenv("CHAINS_PATH"))
+	if err != nil {
+		panic(err)
+	}
 
 	p := make(chans.PChan)
 	l2_hash := make(chans.L2HashChan)
 	root_l2_hash := make(chans.RootL2HashChan)
+	// Progress of every hash through the browsers, for /stream
+	bus := events.NewBus()
 	bm := browser_manager.NewBrowserManager(
 		p,
 		l2_hash,
 		root_l2_hash,
+		bus,
 	)
 	mode := modes.Validate(os.Getenv("MODE"))
 
@@ -37,7 +48,7 @@ func main() {
 	case modes.ModeScan:
 		MainScan(bm)
 	case modes.ModeServer:
-		MainServer(bm, l2_hash)
+		MainServer(bm, l2_hash, bus)
 	}
 
 }
@@ -52,28 +63,44 @@ func MainScan(bm *browser_manager.BrowserManager) {
 	}
 	fmt.Println("Scan pages:", scan_pages)
 
-	scan_from_chain_id := os.Getenv("SCAN_FROM_CHAIN")
-	from_chain_url, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(scan_from_chain_id))
+	scan_from_chain_id := chain.ChainId(os.Getenv("SCAN_FROM_CHAIN"))
+	from_chain_url, from_chain_url_err := chain.MapChainIdUrl(scan_from_chain_id)
 	if from_chain_url_err != nil {
 		panic(from_chain_url_err)
 	}
 	fmt.Println("Scan chain:", from_chain_url)
 
-	bm.StartScan(lm, from_chain_url)
+	bm.StartScan(lm, scan_from_chain_id, from_chain_url)
 	for _, p := range scan_pages {
 		bm.AddP(p)
 	}
 	bm.Wait()
 
 	lm.WriteCsv()
-	latency_avg, latency_max := lm.Agg()
-	fmt.Println("Avg latency:", parse.FormatMs(latency_avg)+"; Max:", parse.FormatMs(latency_max))
+	for i, milestone := range latency_map.Milestones {
+		if i == int(latency_map.Start) {
+			continue
+		}
+		latency_avg, latency_max, count := lm.Agg(latency_map.I(i))
+		if count == 0 {
+			continue
+		}
+		fmt.Println(string(milestone)+":", "Avg latency:", parse.FormatMs(latency_avg)+"; Max:", parse.FormatMs(latency_max), "("+strconv.Itoa(count), "txs)")
+	}
 }
 
-func MainServer(bm *browser_manager.BrowserManager, l2_hash chans.L2HashChan) {
-	server := server.NewServer(l2_hash)
+func MainServer(bm *browser_manager.BrowserManager, l2_hash chans.L2HashChan, bus *events.Bus) {
+	store_path := os.Getenv("STORE_PATH")
+	if store_path == "" {
+		store_path = default_store_path
+	}
+	s := store.NewStore(store_path)
+	defer s.Close()
+
+	workers := server.EnvInt("BROWSER_WORKERS", 1)
+	server := server.NewServer(l2_hash, s, bus)
 
-	bm.StartServer(server)
+	bm.StartServer(server, workers)
 	bm.Wait()
 
 	server.Start()
@@ -3,6 +3,7 @@ package latency_map
 import (
 	"encoding/csv"
 	"fmt"
+	"go-finalityscraper/common/chain"
 	"os"
 	"strconv"
 	"sync"
@@ -11,26 +12,54 @@ import (
 
 type I int
 
+// Milestones, unix ms, empty if unknown
 const (
 	Start I = iota
 	RootEnd
+	RootJustified
+	RootFinalized
+	ChallengeEnd
+	StateProposed
+	StateResolved
+	StateFinalized
+	// Appended, so older csv rows keep their columns
+	ZkTrusted
+	ZkVirtual
+	ZkConsolidated
+	RootPosted
 )
 
-type MV [2]string
+const cols = 12
+
+// Milestone of each I
+var Milestones = [cols]chain.Milestone{
+	chain.MilestoneL2Included,
+	chain.MilestoneL1Posted,
+	chain.MilestoneL1Justified,
+	chain.MilestoneL1Finalized,
+	chain.MilestoneChallengeEnd,
+	chain.MilestoneStateProposed,
+	chain.MilestoneStateResolved,
+	chain.MilestoneStateFinalized,
+	chain.MilestoneZkTrusted,
+	chain.MilestoneZkVirtual,
+	chain.MilestoneZkConsolidated,
+	chain.MilestoneRootPosted,
+}
+
+type MV [cols]string
 type Entry struct {
 	Hash string
 	I    I
 	V    string
 }
 
-const cols = 2
-
 type LatencyMap struct {
 	path string
 
 	// Tx hash -> was in read csv
 	existing_set map[string]bool
-	// Tx hash -> [start, end]
+	// Tx hash -> milestones
 	*sync.Map
 	m_len *atomic.Uint32
 }
@@ -84,28 +113,36 @@ func (lm *LatencyMap) RemoveHash(hash string) {
 	lm.m_len.Store(lm.Len() - 1)
 }
 
-// (mean, max)
-func (lm *LatencyMap) Agg() (float64, float64) {
+// (mean, max, count) of Start -> i, over hashes that reached i
+func (lm *LatencyMap) Agg(i I) (float64, float64, int) {
 	var sum float64 = 0
 	var max float64 = 0
+	count := 0
 	lm.Iter(func(hash string, v *MV) bool {
+		if v[Start] == "" || v[i] == "" {
+			return true
+		}
 		start, start_err := strconv.ParseFloat(v[Start], 64)
 		if start_err != nil {
 			panic(start_err)
 		}
-		root_end, root_end_err := strconv.ParseFloat(v[RootEnd], 64)
-		if root_end_err != nil {
-			panic(root_end_err)
+		end, end_err := strconv.ParseFloat(v[i], 64)
+		if end_err != nil {
+			panic(end_err)
 		}
-		latency := root_end - start
+		latency := end - start
 		sum += latency
 		if latency > max {
 			max = latency
 		}
+		count++
 		return true
 	})
-	mean := sum / float64(lm.Len())
-	return mean, max
+	if count == 0 {
+		return 0, 0, 0
+	}
+	mean := sum / float64(count)
+	return mean, max, count
 }
 
 func (lm *LatencyMap) ReadCsv() [][]string {
@@ -116,6 +153,8 @@ func (lm *LatencyMap) ReadCsv() [][]string {
 	defer file.Close()
 
 	reader := csv.NewReader(file)
+	// Legacy & current rows may be mixed
+	reader.FieldsPerRecord = -1
 
 	csv, err := reader.ReadAll()
 	if err != nil {
@@ -125,26 +164,40 @@ func (lm *LatencyMap) ReadCsv() [][]string {
 	return csv
 }
 
+// Rows are [hash, milestones...]. Legacy files have a [hash, v] row per milestone,
+// Start then RootEnd.
 func (lm *LatencyMap) ParseCsv(csv [][]string) {
-	rows := len(csv)
-	if rows == 0 {
-		return
-	}
+	// Legacy rows read per hash
+	legacy_rows := map[string]int{}
+	for _, record := range csv {
+		if len(record) < 2 {
+			continue
+		}
+		hash := record[0]
+		if !lm.existing_set[hash] {
+			lm.InitHash(hash)
+			lm.existing_set[hash] = true
+		}
 
-	cols := len(csv[0])
-	for row := 0; row < rows; row++ {
-		str_record := csv[row]
-		hash := str_record[0]
-		lm.InitHash(hash)
-		for col := 1; col < cols; col++ {
-			v := csv[row][col]
+		if len(record) == 2 {
+			i := legacy_rows[hash]
+			legacy_rows[hash]++
+			if i < cols {
+				lm.SetHashI(Entry{
+					Hash: hash,
+					I:    I(i),
+					V:    record[1],
+				})
+			}
+			continue
+		}
+		for col := 1; col < len(record) && col <= cols; col++ {
 			lm.SetHashI(Entry{
 				Hash: hash,
 				I:    I(col - 1),
-				V:    v,
+				V:    record[col],
 			})
 		}
-		lm.existing_set[hash] = true
 	}
 }
 
@@ -161,14 +214,11 @@ func (lm *LatencyMap) WriteCsv() {
 		if lm.existing_set[hash] {
 			return true
 		}
-		for col := 0; col < cols; col++ {
-			col_v := v[I(col)]
-			err := writer.Write([]string{hash, col_v})
-			if err != nil {
-				panic(err)
-			}
-			writer.Flush()
+		err := writer.Write(append([]string{hash}, v[:]...))
+		if err != nil {
+			panic(err)
 		}
+		writer.Flush()
 		return true
 	})
 }
@@ -0,0 +1,191 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Hex encoded uint64, e.g. "0x1b4"
+type Quantity uint64
+
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	s := ""
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return err
+	}
+	v, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = Quantity(v)
+	return nil
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.String())
+}
+
+func (q Quantity) String() string {
+	return "0x" + strconv.FormatUint(uint64(q), 16)
+}
+
+func ParseQuantity(s string) (uint64, error) {
+	if !strings.HasPrefix(s, "0x") {
+		return 0, fmt.Errorf("Invalid quantity: %s", s)
+	}
+	return strconv.ParseUint(s[2:], 16, 64)
+}
+
+type Tx struct {
+	Hash string `json:"hash"`
+	// nil while pending
+	BlockNumber *Quantity `json:"blockNumber"`
+	From        string    `json:"from"`
+	// nil for contract creations
+	To    *string  `json:"to"`
+	Input string   `json:"input"`
+	Type  Quantity `json:"type"`
+	// Type 3 only
+	BlobVersionedHashes []string `json:"blobVersionedHashes"`
+	// zkSync Era only, nil until sealed in an L1 batch
+	L1BatchNumber *Quantity `json:"l1BatchNumber"`
+}
+
+// Whether tx was sent to addr
+func (tx Tx) IsTo(addr string) bool {
+	return tx.To != nil && strings.EqualFold(*tx.To, addr)
+}
+
+type Block struct {
+	Number Quantity `json:"number"`
+	// unix s
+	Timestamp Quantity `json:"timestamp"`
+	// Only with full txs
+	Transactions []Tx `json:"transactions"`
+}
+
+// Time of the block, unix ms
+func (block Block) TimeMs() int64 {
+	return int64(block.Timestamp) * 1000
+}
+
+// eth_* calls on any Caller
+type Eth struct {
+	Caller
+}
+
+func NewEth(caller Caller) *Eth {
+	return &Eth{caller}
+}
+
+// nil if the tx is unknown
+func (eth *Eth) GetTransactionByHash(ctx context.Context, hash string) (*Tx, error) {
+	var tx *Tx
+	err := eth.Call(ctx, &tx, "eth_getTransactionByHash", hash)
+	return tx, err
+}
+
+// nil if the block does not exist yet
+func (eth *Eth) GetBlockByNumber(ctx context.Context, number uint64, full bool) (*Block, error) {
+	var block *Block
+	err := eth.Call(ctx, &block, "eth_getBlockByNumber", Quantity(number).String(), full)
+	return block, err
+}
+
+func (eth *Eth) BlockNumber(ctx context.Context) (uint64, error) {
+	var number Quantity
+	err := eth.Call(ctx, &number, "eth_blockNumber")
+	return uint64(number), err
+}
+
+// First block with a timestamp >= ts (unix ms), head+1 if there is none yet
+func (eth *Eth) BlockAtTime(ctx context.Context, ts int64) (uint64, error) {
+	head, err := eth.BlockNumber(ctx)
+	if err != nil {
+		return 0, err
+	}
+	lo, hi := uint64(0), head+1
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		block, err := eth.GetBlockByNumber(ctx, mid, false)
+		if err != nil {
+			return 0, err
+		}
+		if block == nil {
+			return 0, fmt.Errorf("Block not found: %d", mid)
+		}
+		if block.TimeMs() < ts {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, nil
+}
+
+type Log struct {
+	Address     string   `json:"address"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+	BlockNumber Quantity `json:"blockNumber"`
+	TxHash      string   `json:"transactionHash"`
+}
+
+type Receipt struct {
+	BlockNumber Quantity `json:"blockNumber"`
+	// 1 success, 0 reverted
+	Status Quantity `json:"status"`
+	Logs   []Log    `json:"logs"`
+}
+
+// nil while the tx is pending or unknown
+func (eth *Eth) GetTransactionReceipt(ctx context.Context, hash string) (*Receipt, error) {
+	var receipt *Receipt
+	err := eth.Call(ctx, &receipt, "eth_getTransactionReceipt", hash)
+	return receipt, err
+}
+
+// eth_getLogs params, blocks inclusive
+type LogFilter struct {
+	FromBlock Quantity `json:"fromBlock"`
+	ToBlock   Quantity `json:"toBlock"`
+	Address   string   `json:"address,omitempty"`
+	// Per position: nil matches any topic, a []string any of them
+	Topics []any `json:"topics,omitempty"`
+}
+
+// Topic filter by position, "" matches any topic
+func Topics(topics ...string) []any {
+	filter := []any{}
+	for _, topic := range topics {
+		if topic == "" {
+			filter = append(filter, nil)
+			continue
+		}
+		filter = append(filter, topic)
+	}
+	return filter
+}
+
+func (eth *Eth) GetLogs(ctx context.Context, filter LogFilter) ([]Log, error) {
+	logs := []Log{}
+	err := eth.Call(ctx, &logs, "eth_getLogs", filter)
+	return logs, err
+}
+
+// eth_call params
+type CallMsg struct {
+	To   string `json:"to"`
+	Data string `json:"data"`
+}
+
+// eth_call of data ("0x" prefixed) on to, at the latest block
+func (eth *Eth) CallContract(ctx context.Context, to string, data string) (string, error) {
+	result := ""
+	err := eth.Call(ctx, &result, "eth_call", CallMsg{To: to, Data: data}, "latest")
+	return result, err
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const call_timeout = 30 * time.Second
+
+// Anything answering JSON-RPC calls, e.g. a node or an explorer proxy
+type Caller interface {
+	Call(ctx context.Context, result any, method string, params ...any) error
+}
+
+type req struct {
+	JsonRpc string `json:"jsonrpc"`
+	Id      uint64 `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type res struct {
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("RPC error %d: %s", err.Code, err.Message)
+}
+
+// JSON-RPC 2.0 over HTTP
+type Client struct {
+	url    string
+	client *http.Client
+	id     *atomic.Uint64
+}
+
+func NewClient(url string) *Client {
+	return &Client{
+		url:    url,
+		client: &http.Client{Timeout: call_timeout},
+		id:     &atomic.Uint64{},
+	}
+}
+
+// Decodes the result of method into result, null results leave it untouched
+func (c *Client) Call(ctx context.Context, result any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(req{
+		JsonRpc: "2.0",
+		Id:      c.id.Add(1),
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		return err
+	}
+
+	http_req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	http_req.Header.Set("Content-Type", "application/json")
+
+	http_res, err := c.client.Do(http_req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: %w", method, err))
+	}
+	defer http_res.Body.Close()
+	if http_res.StatusCode != http.StatusOK {
+		return status.NewErr(status.ErrCodeExplorerUnavailable, fmt.Errorf("Error calling %s: status %d", method, http_res.StatusCode))
+	}
+
+	r := res{}
+	err = json.NewDecoder(http_res.Body).Decode(&r)
+	if err != nil {
+		return status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding %s: %w", method, err))
+	}
+	// Errors of the node, e.g. -32603 internal errors, may pass on retry
+	if r.Error != nil {
+		return status.NewErr(status.ErrCodeExplorerUnavailable, r.Error)
+	}
+	return Decode(r.Result, result)
+}
+
+// null stays the zero value of result. Results of another shape are scrape_failed,
+// asking again returns the same.
+func Decode(raw json.RawMessage, result any) error {
+	if result == nil || len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	err := json.Unmarshal(raw, result)
+	if err != nil {
+		return status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Error decoding result: %w", err))
+	}
+	return nil
+}
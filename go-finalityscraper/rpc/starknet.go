@@ -0,0 +1,62 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+)
+
+// Starknet finality statuses
+const (
+	StarknetAcceptedOnL2 = "ACCEPTED_ON_L2"
+	// State update covering the block verified on L1
+	StarknetAcceptedOnL1 = "ACCEPTED_ON_L1"
+)
+
+// TXN_HASH_NOT_FOUND
+const starknet_err_tx_not_found = 29
+
+type StarknetReceipt struct {
+	FinalityStatus  string `json:"finality_status"`
+	ExecutionStatus string `json:"execution_status"`
+	// nil while pending
+	BlockNumber *uint64 `json:"block_number"`
+}
+
+type StarknetBlock struct {
+	BlockNumber uint64 `json:"block_number"`
+	// unix s
+	Timestamp    int64    `json:"timestamp"`
+	Transactions []string `json:"transactions"`
+}
+
+// Time of the block, unix ms
+func (block StarknetBlock) TimeMs() int64 {
+	return block.Timestamp * 1000
+}
+
+// starknet_* calls on any Caller
+type Starknet struct {
+	Caller
+}
+
+func NewStarknet(caller Caller) *Starknet {
+	return &Starknet{caller}
+}
+
+// nil if the tx is unknown
+func (sn *Starknet) GetTransactionReceipt(ctx context.Context, hash string) (*StarknetReceipt, error) {
+	var receipt *StarknetReceipt
+	err := sn.Call(ctx, &receipt, "starknet_getTransactionReceipt", hash)
+	var rpc_err *Error
+	if errors.As(err, &rpc_err) && rpc_err.Code == starknet_err_tx_not_found {
+		return nil, nil
+	}
+	return receipt, err
+}
+
+// nil if the block does not exist yet
+func (sn *Starknet) GetBlockWithTxHashes(ctx context.Context, number uint64) (*StarknetBlock, error) {
+	var block *StarknetBlock
+	err := sn.Call(ctx, &block, "starknet_getBlockWithTxHashes", map[string]uint64{"block_number": number})
+	return block, err
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+)
+
+// What the node returns for L1 txs that did not happen yet
+const zero_hash = "0x0000000000000000000000000000000000000000000000000000000000000000"
+
+// zkevm_getBatchByNumber
+type ZkEvmBatch struct {
+	Number Quantity `json:"number"`
+	// unix s, when the trusted sequencer closed it
+	Timestamp Quantity `json:"timestamp"`
+	// nil or zero until the batch is sequenced (virtual) & verified (consolidated) on L1
+	SendSequencesTxHash *string `json:"sendSequencesTxHash"`
+	VerifyBatchTxHash   *string `json:"verifyBatchTxHash"`
+}
+
+// Time of the batch, unix ms
+func (batch ZkEvmBatch) TimeMs() int64 {
+	return int64(batch.Timestamp) * 1000
+}
+
+// L1 tx sequencing the batch, empty until it is virtual
+func (batch ZkEvmBatch) SequenceTx() string {
+	return zkEvmTx(batch.SendSequencesTxHash)
+}
+
+// L1 tx verifying the batch, empty until it is consolidated
+func (batch ZkEvmBatch) VerifyTx() string {
+	return zkEvmTx(batch.VerifyBatchTxHash)
+}
+
+func zkEvmTx(hash *string) string {
+	if hash == nil || strings.EqualFold(*hash, zero_hash) {
+		return ""
+	}
+	return *hash
+}
+
+// Polygon zkEVM zkevm_* calls on any Caller
+type ZkEvm struct {
+	Caller
+}
+
+func NewZkEvm(caller Caller) *ZkEvm {
+	return &ZkEvm{caller}
+}
+
+func (zkevm *ZkEvm) BatchNumberByBlockNumber(ctx context.Context, block uint64) (uint64, error) {
+	var number Quantity
+	err := zkevm.Call(ctx, &number, "zkevm_batchNumberByBlockNumber", Quantity(block))
+	return uint64(number), err
+}
+
+// nil if the batch is not closed yet
+func (zkevm *ZkEvm) GetBatchByNumber(ctx context.Context, number uint64) (*ZkEvmBatch, error) {
+	var batch *ZkEvmBatch
+	err := zkevm.Call(ctx, &batch, "zkevm_getBatchByNumber", Quantity(number), false)
+	return batch, err
+}
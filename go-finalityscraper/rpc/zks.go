@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+	"time"
+)
+
+// zks_getTransactionDetails
+type ZksTxDetails struct {
+	// pending | included | verified | failed
+	Status string `json:"status"`
+	// nil until the L1 batch of the tx is committed, proven, executed
+	EthCommitTxHash  *string `json:"ethCommitTxHash"`
+	EthProveTxHash   *string `json:"ethProveTxHash"`
+	EthExecuteTxHash *string `json:"ethExecuteTxHash"`
+}
+
+// zks_getL1BatchDetails
+type ZksL1Batch struct {
+	Number uint64 `json:"number"`
+	// unix s
+	Timestamp uint64 `json:"timestamp"`
+	// sealed | verified
+	Status string `json:"status"`
+	// nil until the step happened on L1
+	CommitTxHash  *string    `json:"commitTxHash"`
+	CommittedAt   *time.Time `json:"committedAt"`
+	ProveTxHash   *string    `json:"proveTxHash"`
+	ProvenAt      *time.Time `json:"provenAt"`
+	ExecuteTxHash *string    `json:"executeTxHash"`
+	ExecutedAt    *time.Time `json:"executedAt"`
+}
+
+// zkSync Era zks_* calls on any Caller
+type Zks struct {
+	Caller
+}
+
+func NewZks(caller Caller) *Zks {
+	return &Zks{caller}
+}
+
+// nil if the tx is unknown
+func (zks *Zks) GetTransactionDetails(ctx context.Context, hash string) (*ZksTxDetails, error) {
+	var details *ZksTxDetails
+	err := zks.Call(ctx, &details, "zks_getTransactionDetails", hash)
+	return details, err
+}
+
+// nil if the batch is not sealed yet
+func (zks *Zks) GetL1BatchDetails(ctx context.Context, number uint64) (*ZksL1Batch, error) {
+	var batch *ZksL1Batch
+	err := zks.Call(ctx, &batch, "zks_getL1BatchDetails", number)
+	return batch, err
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+const batch_max_items = 100
+
+type RootEndItemRes struct {
+	FromChain string `json:"from_chain"`
+	Hash      string `json:"hash"`
+	Code      int    `json:"code"`
+	RootEnd   string `json:"root_end,omitempty"`
+	Err       string `json:"err,omitempty"`
+	// See ErrRes
+	ErrCode status.ErrCode  `json:"err_code,omitempty"`
+	Status  status.TxStatus `json:"status,omitempty"`
+	Retry   bool            `json:"retry,omitempty"`
+}
+
+func (sv *Server) root_end_batch_POST(c echo.Context) error {
+	reqs := []RootEndReq{}
+	bind_err := c.Bind(&reqs)
+	if bind_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, bind_err)
+	}
+	if len(reqs) > batch_max_items {
+		return errJSON(c, status.ErrCodeInvalidRequest, fmt.Errorf("Too many items: %d > %d", len(reqs), batch_max_items))
+	}
+
+	timeout, timeout_err := sv.requestTimeout(c.QueryParam("timeout"))
+	if timeout_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, timeout_err)
+	}
+
+	uniq_reqs, uniq_idxs := uniqueItems(reqs)
+	uniq_ress := make([]RootEndItemRes, len(uniq_reqs))
+	sem := make(chan struct{}, sv.batch_concurrency)
+	wg := &sync.WaitGroup{}
+	for i, req := range uniq_reqs {
+		wg.Add(1)
+		go func(i int, req RootEndReq) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// Each item has its own deadline from the time it is resolved
+			ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+			defer cancel()
+			uniq_ress[i] = sv.resolveItem(ctx, req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	item_ress := make([]RootEndItemRes, len(reqs))
+	for i, req := range reqs {
+		item_ress[i] = uniq_ress[uniq_idxs[i]]
+		item_ress[i].FromChain = req.FromChain
+		item_ress[i].Hash = req.Hash
+	}
+	return c.JSON(http.StatusOK, item_ress)
+}
+
+// Items without duplicates, and the index in them of each item
+func uniqueItems(reqs []RootEndReq) ([]RootEndReq, []int) {
+	uniq_reqs := []RootEndReq{}
+	uniq_idxs := make([]int, len(reqs))
+	key_idxs := map[string]int{}
+	for i, req := range reqs {
+		// Hashes are case insensitive, as in chain.L2Hash.Key
+		key := req.FromChain + "/" + strings.ToLower(req.Hash) + "/" + string(req.Milestone)
+		idx, idx_ok := key_idxs[key]
+		if !idx_ok {
+			idx = len(uniq_reqs)
+			key_idxs[key] = idx
+			uniq_reqs = append(uniq_reqs, req)
+		}
+		uniq_idxs[i] = idx
+	}
+	return uniq_reqs, uniq_idxs
+}
+
+func (sv *Server) resolveItem(ctx context.Context, req RootEndReq) RootEndItemRes {
+	item_res := RootEndItemRes{
+		FromChain: req.FromChain,
+		Hash:      req.Hash,
+	}
+
+	milestone, milestone_err := chain.ParseMilestone(string(req.Milestone))
+	if milestone_err != nil {
+		item_res.ErrCode = status.ErrCodeInvalidRequest
+		item_res.Code = httpCode(item_res.ErrCode)
+		item_res.Err = milestone_err.Error()
+		return item_res
+	}
+
+	from_chain_url, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(req.FromChain))
+	if from_chain_url_err != nil {
+		item_res.ErrCode = status.CodeOf(from_chain_url_err)
+		item_res.Code = httpCode(item_res.ErrCode)
+		item_res.Err = from_chain_url_err.Error()
+		return item_res
+	}
+
+	code, v := mapRes(sv.wait(ctx, sv.dispatch(chain.L2Hash{
+		ChainId:  chain.ChainId(req.FromChain),
+		ChainUrl: from_chain_url,
+		Hash:     req.Hash,
+	})), milestone, rootEndRes)
+	item_res.Code = code
+	switch v := v.(type) {
+	case RootEndRes:
+		item_res.RootEnd = v.RootEnd
+		item_res.Status = status.TxStatusL1Included
+	case ErrRes:
+		item_res.Err = v.Err
+		item_res.ErrCode = v.Code
+		item_res.Status = v.Status
+		item_res.Retry = v.Retry
+	}
+	return item_res
+}
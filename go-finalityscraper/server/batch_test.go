@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/store"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+func postBatch(t *testing.T, sv *Server, query string, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/root_end/batch"+query, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	err := sv.root_end_batch_POST(e.NewContext(req, rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rec
+}
+
+func TestRootEndBatchMaxItems(t *testing.T) {
+	items := make([]string, batch_max_items+1)
+	for i := range items {
+		items[i] = fmt.Sprintf(`{"from_chain":"10","hash":"0x%x"}`, i)
+	}
+
+	rec := postBatch(t, &Server{}, "", "["+strings.Join(items, ",")+"]")
+	res := ErrRes{}
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	if rec.Code != http.StatusBadRequest || res.Code != status.ErrCodeInvalidRequest {
+		t.Fatalf("got %d %+v, want %d %s", rec.Code, res, http.StatusBadRequest, status.ErrCodeInvalidRequest)
+	}
+}
+
+func TestRootEndBatchDedup(t *testing.T) {
+	l2_hash := make(chan chain.L2Hash)
+	sv := &Server{
+		l2_hash:           l2_hash,
+		res_map:           &ResMap{&sync.Map{}},
+		cache:             store.NewCache(store.NewStore(filepath.Join(t.TempDir(), "test.db"))),
+		root_end_timeout:  time.Minute,
+		batch_concurrency: 1,
+	}
+
+	// 0xaa times out unanswered, 0xbb is answered once 0xaa's deadline passed
+	lookups := make(chan []string)
+	go func() {
+		hashes := []string{}
+		for len(hashes) < 2 {
+			select {
+			case next := <-l2_hash:
+				hashes = append(hashes, next.Hash)
+				if next.Hash != "0xbb" {
+					continue
+				}
+				time.Sleep(50 * time.Millisecond)
+				latency, err := NewLatencyV(chain.RootL2Hash{L2Hash: next, Href: "https://etherscan.io/tx/0xbatch", Start: 1_000_000}, chain.RootTx{RootEnd: 5_000_000})
+				if err != nil {
+					t.Error(err)
+				}
+				sv.SetChanRes(next, latency)
+			case <-time.After(time.Second):
+				lookups <- hashes
+				return
+			}
+		}
+		lookups <- hashes
+	}()
+
+	rec := postBatch(t, sv, "?timeout=100ms", `[
+		{"from_chain":"10","hash":"0xaa"},
+		{"from_chain":"10","hash":"0xbb"},
+		{"from_chain":"10","hash":"0xAA"},
+		{"from_chain":"10","hash":"0xaa","milestone":"l1_final"}
+	]`)
+	ress := []RootEndItemRes{}
+	json.Unmarshal(rec.Body.Bytes(), &ress)
+	if rec.Code != http.StatusOK || len(ress) != 4 {
+		t.Fatalf("got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Each lookup once, 0xAA is not looked up again after 0xaa timed out
+	hashes := <-lookups
+	if strings.Join(hashes, ",") != "0xaa,0xbb" {
+		t.Fatalf("got lookups %v, want [0xaa 0xbb]", hashes)
+	}
+	for i, hash := range []string{"0xaa", "0xbb", "0xAA", "0xaa"} {
+		if ress[i].Hash != hash {
+			t.Fatalf("item %d: got hash %s, want %s", i, ress[i].Hash, hash)
+		}
+	}
+	if ress[0].Code == http.StatusOK || ress[2].Err != ress[0].Err {
+		t.Fatalf("got %+v, want 0xaa & 0xAA timed out", ress)
+	}
+	// Own deadline per item, from when it is resolved
+	if ress[1].Code != http.StatusOK || ress[1].RootEnd != "5000000" {
+		t.Fatalf("got %+v, want 0xbb resolved", ress[1])
+	}
+	if ress[3].ErrCode != status.ErrCodeInvalidRequest {
+		t.Fatalf("got %+v, want %s", ress[3], status.ErrCodeInvalidRequest)
+	}
+}
+
+func TestRootEndBatchInvalidTimeout(t *testing.T) {
+	rec := postBatch(t, &Server{}, "?timeout=soon", `[{"from_chain":"10","hash":"0xaa"}]`)
+	res := ErrRes{}
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	if rec.Code != http.StatusBadRequest || res.Code != status.ErrCodeInvalidRequest {
+		t.Fatalf("got %d %+v, want %d %s", rec.Code, res, http.StatusBadRequest, status.ErrCodeInvalidRequest)
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/source"
+	"go-finalityscraper/store"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+type MetricsRes struct {
+	Cache store.CacheStats `json:"cache"`
+}
+
+type InvalidateRes struct {
+	Deleted int `json:"deleted"`
+}
+
+// Only entries served count as hits, not those missing milestones that may be known by now
+func (sv *Server) getCache(l2_hash chain.L2Hash) (LatencyV, bool) {
+	v, hit := sv.cachedLatency(l2_hash)
+	sv.cache.Count(hit)
+	return v, hit
+}
+
+func (sv *Server) cachedLatency(l2_hash chain.L2Hash) (LatencyV, bool) {
+	latency := LatencyRes{}
+	// Entries cached before the full latency record have no l1_timestamp
+	if !sv.cache.Get(l2_hash.ChainId, l2_hash.Hash, &latency) || latency.L1Timestamp == 0 {
+		return LatencyV{}, false
+	}
+	latency.Status = status.TxStatusL1Included
+	// Cached before milestones
+	if latency.Milestones == nil {
+		latency.Milestone = chain.MilestoneL1Posted
+		latency.Milestones = chain.RootTx{RootEnd: latency.L1Timestamp}.Milestones(l2_hash.ChainId, latency.L2Timestamp)
+	}
+	// Beacon finality & settlement may be known by now
+	if latency.Milestones[chain.MilestoneL1Finalized] == 0 && hasBeacon(l2_hash.ChainId) {
+		return LatencyV{}, false
+	}
+	if latency.Milestones[chain.MilestoneStateFinalized] == 0 && sv.hasSettler(l2_hash.ChainId) {
+		return LatencyV{}, false
+	}
+	if latency.Milestones[chain.MilestoneRootPosted] == 0 && hasHops(l2_hash.ChainId) {
+		return LatencyV{}, false
+	}
+	return LatencyV{
+		HasCode: HasCode{
+			Code: http.StatusOK,
+		},
+		LatencyRes: latency,
+	}, true
+}
+
+func hasBeacon(chain_id chain.ChainId) bool {
+	root_chain_id, root_chain_id_err := chain.MapChainIdRoot(chain_id)
+	return root_chain_id_err == nil && source.EnvBeaconUrl(root_chain_id) != ""
+}
+
+// Memoised per chain, settlers only depend on the registry & .env
+func (sv *Server) hasSettler(chain_id chain.ChainId) bool {
+	has, has_exists := sv.has_settler.Load(chain_id)
+	if has_exists {
+		return has.(bool)
+	}
+	settler, _ := source.NewSettler(chain_id)
+	sv.has_settler.Store(chain_id, settler != nil)
+	return settler != nil
+}
+
+// Whether the parent of chain_id is an L2 too
+func hasHops(chain_id chain.ChainId) bool {
+	parents, _ := chain.MapChainIdParents(chain_id)
+	return len(parents) > 1
+}
+
+// Only successful results are cached, errors may resolve later
+func (sv *Server) setCache(l2_hash chain.L2Hash, v any) {
+	latency, latency_ok := v.(LatencyV)
+	if !latency_ok {
+		return
+	}
+	err := sv.cache.Set(l2_hash.ChainId, l2_hash.Hash, latency.LatencyRes)
+	if err != nil {
+		fmt.Println("Error caching", l2_hash.Hash+":", err)
+	}
+}
+
+func (sv *Server) metrics_GET(c echo.Context) error {
+	return c.JSON(http.StatusOK, MetricsRes{
+		Cache: sv.cache.Stats(),
+	})
+}
+
+// Without hash, invalidates every hash of from_chain
+func (sv *Server) admin_cache_DELETE(c echo.Context) error {
+	from_chain_id := chain.ChainId(c.QueryParam("from_chain"))
+	_, from_chain_url_err := chain.MapChainIdUrl(from_chain_id)
+	if from_chain_url_err != nil {
+		return errJSON(c, status.CodeOf(from_chain_url_err), from_chain_url_err)
+	}
+
+	deleted, err := sv.cache.Invalidate(from_chain_id, c.QueryParam("hash"))
+	if err != nil {
+		return errJSON(c, status.ErrCodeUnknown, err)
+	}
+	return c.JSON(http.StatusOK, InvalidateRes{
+		Deleted: deleted,
+	})
+}
@@ -0,0 +1,13 @@
+package server
+
+import (
+	"go-finalityscraper/common/chain"
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// Every chain of the registry, L2s & root chains
+func (sv *Server) chains_GET(c echo.Context) error {
+	return c.JSON(http.StatusOK, chain.Chains())
+}
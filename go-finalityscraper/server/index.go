@@ -1,11 +1,19 @@
 package server
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
+	"go-finalityscraper/common/chain"
 	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/store"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/labstack/echo"
 	"github.com/labstack/echo/middleware"
@@ -15,41 +23,142 @@ type HasCode struct {
 	Code int
 }
 type ErrRes struct {
-	Err string `json:"err"`
+	Err    string          `json:"err"`
+	Code   status.ErrCode  `json:"code"`
+	Status status.TxStatus `json:"status,omitempty"`
+	// Whether the same request may succeed later
+	Retry bool `json:"retry"`
 }
 type HasErr struct {
 	HasCode
 	ErrRes
 }
 
-func NewHasErr(code int, err error) HasErr {
+func NewErrRes(code status.ErrCode, err error) ErrRes {
+	return ErrRes{
+		Err:    err.Error(),
+		Code:   code,
+		Status: code.TxStatus(),
+		Retry:  code.Retry(),
+	}
+}
+
+// Classified by status.CodeOf(err)
+func NewHasErr(err error) HasErr {
+	code := status.CodeOf(err)
 	return HasErr{
 		HasCode: HasCode{
-			Code: code,
-		},
-		ErrRes: ErrRes{
-			Err: err.Error(),
+			Code: httpCode(code),
 		},
+		ErrRes: NewErrRes(code, err),
+	}
+}
+
+func httpCode(code status.ErrCode) int {
+	switch code {
+	case status.ErrCodeUnknownChain, status.ErrCodeInvalidRequest:
+		return http.StatusBadRequest
+	case status.ErrCodeNotFound, status.ErrCodeTxNotFound:
+		return http.StatusNotFound
+	case status.ErrCodeChainDeprecated:
+		return http.StatusGone
+	case status.ErrCodeNotBatched, status.ErrCodeL1Unconfirmed, status.ErrCodeMilestonePending:
+		return http.StatusTooEarly
+	case status.ErrCodeExplorerUnavailable, status.ErrCodeScrapeFailed:
+		return http.StatusBadGateway
+	case status.ErrCodeTimeout:
+		return http.StatusGatewayTimeout
+	case status.ErrCodeDisabled:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
 	}
 }
 
+func errJSON(c echo.Context, code status.ErrCode, err error) error {
+	return c.JSON(httpCode(code), NewErrRes(code, err))
+}
+
+// In-flight result of one scrape, shared by every request for the same key
+type resProcess struct {
+	key string
+	// Cancelled once every waiting request has given up
+	ctx    context.Context
+	cancel context.CancelFunc
+	// Closed once l2_b picked up the hash
+	sent chans.DoneChan
+	done chans.DoneChan
+	// LatencyV | HasErr
+	result any
+
+	mu   *sync.Mutex
+	refs int
+}
+
+// Adds a waiting request, false if the process was already abandoned
+func (process *resProcess) acquire() bool {
+	process.mu.Lock()
+	defer process.mu.Unlock()
+	if process.refs == 0 {
+		return false
+	}
+	process.refs++
+	return true
+}
+
+// chain.L2Hash.Key() -> *resProcess
 type ResMap struct {
 	*sync.Map
 }
 
 type Server struct {
 	l2_hash chans.L2HashChan
+	bus     *events.Bus
 
 	e       *echo.Echo
 	res_map *ResMap
+	job_map *JobMap
+	store   *store.Store
+	cache   *store.Cache
+	// Chain id -> whether its settlement is read, see hasSettler
+	has_settler *sync.Map
+
+	// Deadlines
+	root_end_timeout time.Duration
+	job_timeout      time.Duration
+
+	batch_concurrency int
+
+	// Webhooks
+	watch_secret       string
+	watch_interval     time.Duration
+	watch_max_attempts int
+	// Guards watches against the API and the watch loop writing at once
+	watch_mu *sync.Mutex
 }
 
-func NewServer(l2_hash chans.L2HashChan) *Server {
+func NewServer(l2_hash chans.L2HashChan, s *store.Store, bus *events.Bus) *Server {
 	sv := &Server{
 		l2_hash: l2_hash,
+		bus:     bus,
 
 		e:       echo.New(),
 		res_map: &ResMap{&sync.Map{}},
+		job_map: &JobMap{&sync.Map{}},
+		store:   s,
+		cache:   store.NewCache(s),
+
+		has_settler: &sync.Map{},
+
+		root_end_timeout: envDuration("ROOT_END_TIMEOUT", time.Minute),
+		job_timeout:      envDuration("JOB_TIMEOUT", 10*time.Minute),
+
+		batch_concurrency: EnvInt("BATCH_CONCURRENCY", 4),
+
+		watch_secret:       os.Getenv("WATCH_SECRET"),
+		watch_interval:     envDuration("WATCH_INTERVAL", time.Minute),
+		watch_max_attempts: EnvInt("WATCH_MAX_ATTEMPTS", 5),
+		watch_mu:           &sync.Mutex{},
 	}
 
 	sv.e.Use(middleware.Logger())
@@ -64,37 +173,172 @@ func NewServer(l2_hash chans.L2HashChan) *Server {
 	})
 
 	sv.e.GET("/root_end", sv.root_end_GET)
+	sv.e.POST("/root_end/batch", sv.root_end_batch_POST)
+	sv.e.GET("/latency", sv.latency_GET)
+	sv.e.GET("/stream", sv.stream_GET)
+
+	sv.e.POST("/jobs", sv.jobs_POST)
+	sv.e.GET("/jobs/:id", sv.jobs_GET)
+
+	sv.e.GET("/metrics", sv.metrics_GET)
+	sv.e.GET("/chains", sv.chains_GET)
+
+	// Answer disabled without WATCH_SECRET, rather than 404
+	sv.e.POST("/watch", sv.watch_POST, sv.watchEnabled)
+	sv.e.GET("/watch/:id", sv.watch_GET, sv.watchEnabled)
+	sv.e.DELETE("/watch/:id", sv.watch_DELETE, sv.watchEnabled)
+
+	admin_token := os.Getenv("ADMIN_TOKEN")
+	if admin_token != "" {
+		admin := sv.e.Group("/admin", middleware.KeyAuth(func(key string, c echo.Context) (bool, error) {
+			return subtle.ConstantTimeCompare([]byte(key), []byte(admin_token)) == 1, nil
+		}))
+		admin.DELETE("/cache", sv.admin_cache_DELETE)
+	}
 
 	return sv
 }
 
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid %s: %s", key, v))
+	}
+	return d
+}
+
+func EnvInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 1 {
+		panic(fmt.Sprintf("Invalid %s: %s", key, v))
+	}
+	return i
+}
+
 func (sv *Server) Start() {
 	httpPort := os.Getenv("PORT")
 	if httpPort == "" {
 		httpPort = "8080"
 	}
+	if sv.watch_secret != "" {
+		go sv.watchLoop()
+	} else {
+		fmt.Println("WATCH_SECRET not set, /watch is disabled")
+	}
 	sv.e.Logger.Fatal(sv.e.Start(":" + httpPort))
 }
 
-func (sv *Server) getResChan(id string) (chans.AnyChan, bool) {
-	res_chan, res_chan_exists := sv.res_map.Load(id)
-	if res_chan_exists {
-		return res_chan.(chans.AnyChan), true
+func (sv *Server) getRes(key string) (*resProcess, bool) {
+	process, process_exists := sv.res_map.Load(key)
+	if process_exists {
+		return process.(*resProcess), true
 	}
 	return nil, false
 }
 
-func (sv *Server) initResChan(id string) chans.AnyChan {
-	root_end_chan := make(chans.AnyChan)
-	sv.res_map.Store(id, root_end_chan)
-	return root_end_chan
+func newResProcess(key string) *resProcess {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &resProcess{
+		key:    key,
+		ctx:    ctx,
+		cancel: cancel,
+		sent:   make(chans.DoneChan),
+		done:   make(chans.DoneChan),
+		mu:     &sync.Mutex{},
+		refs:   1,
+	}
 }
 
-func (sv *Server) SetChanRes(id string, v any) error {
-	res_chan, res_chan_exists := sv.getResChan(id)
-	if res_chan_exists {
-		res_chan <- v
-		return nil
+// Returns the in-flight process for key, or a new one if there is none (true)
+func (sv *Server) initRes(key string) (*resProcess, bool) {
+	for {
+		new_process := newResProcess(key)
+		process, process_exists := sv.res_map.LoadOrStore(key, new_process)
+		if !process_exists {
+			return new_process, true
+		}
+		new_process.cancel()
+		if process.(*resProcess).acquire() {
+			return process.(*resProcess), false
+		}
+		// Abandoned in the meantime, retry once it is removed
 	}
-	return fmt.Errorf("res_map id not found: %s", id)
+}
+
+// Removes a waiting request, cancels the scrape once nobody waits for it
+func (sv *Server) releaseRes(process *resProcess) {
+	process.mu.Lock()
+	defer process.mu.Unlock()
+	process.refs--
+	if process.refs == 0 {
+		process.cancel()
+		sv.res_map.CompareAndDelete(process.key, process)
+	}
+}
+
+// Delivers v to every request waiting on l2_hash, then forgets it
+func (sv *Server) SetChanRes(l2_hash chain.L2Hash, v any) error {
+	key := l2_hash.Key()
+	process, process_exists := sv.getRes(key)
+	// An abandoned scrape must not answer a newer one for the same key
+	if !process_exists || process.ctx != l2_hash.Ctx {
+		return fmt.Errorf("res_map key not found: %s", key)
+	}
+	// Cached before forgetting key, so no new scrape starts in between
+	sv.setCache(l2_hash, v)
+	if sv.res_map.CompareAndDelete(key, process) {
+		process.result = v
+		close(process.done)
+		process.cancel()
+	}
+	return nil
+}
+
+// Sends l2_hash through the browsers, unless the same (chain, hash) is already in flight
+func (sv *Server) dispatch(l2_hash chain.L2Hash) *resProcess {
+	cached, cached_ok := sv.getCache(l2_hash)
+	if cached_ok {
+		process := newResProcess(l2_hash.Key())
+		process.result = cached
+		close(process.sent)
+		close(process.done)
+		process.cancel()
+		return process
+	}
+
+	process, process_new := sv.initRes(l2_hash.Key())
+	if process_new {
+		l2_hash.Ctx = process.ctx
+		go func() {
+			select {
+			case sv.l2_hash <- l2_hash:
+				close(process.sent)
+			case <-process.ctx.Done():
+			}
+		}()
+	}
+	return process
+}
+
+// Waits for the result of process until ctx is done
+func (sv *Server) wait(ctx context.Context, process *resProcess) any {
+	select {
+	case <-process.done:
+		return process.result
+	case <-ctx.Done():
+		sv.releaseRes(process)
+		return timedOut(ctx)
+	}
+}
+
+func timedOut(ctx context.Context) HasErr {
+	return NewHasErr(fmt.Errorf("Timed out waiting for root end: %w", ctx.Err()))
 }
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// How long a finished job stays queryable
+const job_ttl = time.Hour
+
+type JobStatus string
+
+const (
+	JobStatusQueued  JobStatus = "queued"
+	JobStatusRunning JobStatus = "running"
+	JobStatusDone    JobStatus = "done"
+	JobStatusFailed  JobStatus = "failed"
+)
+
+type JobRes struct {
+	Id     string      `json:"id"`
+	Status JobStatus   `json:"status"`
+	Result *RootEndRes `json:"result,omitempty"`
+	Err    string      `json:"err,omitempty"`
+	// See ErrRes
+	ErrCode status.ErrCode `json:"err_code,omitempty"`
+	Retry   bool           `json:"retry,omitempty"`
+}
+
+type Job struct {
+	mu  *sync.Mutex
+	res JobRes
+}
+
+func (j *Job) Res() JobRes {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.res
+}
+
+func (j *Job) setStatus(status JobStatus) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.res.Status = status
+}
+
+func (j *Job) setRes(res any, milestone chain.Milestone) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, v := mapRes(res, milestone, rootEndRes)
+	switch v := v.(type) {
+	case RootEndRes:
+		j.res.Status = JobStatusDone
+		j.res.Result = &v
+	case ErrRes:
+		j.res.Status = JobStatusFailed
+		j.res.Err = v.Err
+		j.res.ErrCode = v.Code
+		j.res.Retry = v.Retry
+	}
+}
+
+type JobMap struct {
+	*sync.Map
+}
+
+func (m *JobMap) Get(id string) (*Job, bool) {
+	job, job_exists := m.Load(id)
+	if job_exists {
+		return job.(*Job), true
+	}
+	return nil, false
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (sv *Server) jobs_POST(c echo.Context) error {
+	req := RootEndReq{}
+	bind_err := c.Bind(&req)
+	if bind_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, bind_err)
+	}
+
+	from_chain_url, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(req.FromChain))
+	if from_chain_url_err != nil {
+		return errJSON(c, status.CodeOf(from_chain_url_err), from_chain_url_err)
+	}
+
+	milestone, milestone_err := chain.ParseMilestone(string(req.Milestone))
+	if milestone_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, milestone_err)
+	}
+
+	id, id_err := newId()
+	if id_err != nil {
+		return errJSON(c, status.ErrCodeUnknown, id_err)
+	}
+
+	job := &Job{
+		mu: &sync.Mutex{},
+		res: JobRes{
+			Id:     id,
+			Status: JobStatusQueued,
+		},
+	}
+	sv.job_map.Store(id, job)
+
+	go sv.runJob(job, chain.L2Hash{
+		ChainId:  chain.ChainId(req.FromChain),
+		ChainUrl: from_chain_url,
+		Hash:     req.Hash,
+	}, milestone)
+
+	return c.JSON(http.StatusAccepted, job.Res())
+}
+
+func (sv *Server) jobs_GET(c echo.Context) error {
+	id := c.Param("id")
+	job, job_exists := sv.job_map.Get(id)
+	if !job_exists {
+		return errJSON(c, status.ErrCodeNotFound, fmt.Errorf("Job not found: %s", id))
+	}
+	return c.JSON(http.StatusOK, job.Res())
+}
+
+// Resolves l2_hash in the background, the result is the timestamp of milestone
+func (sv *Server) runJob(job *Job, l2_hash chain.L2Hash, milestone chain.Milestone) {
+	ctx, cancel := context.WithTimeout(context.Background(), sv.job_timeout)
+	defer cancel()
+
+	process := sv.dispatch(l2_hash)
+	select {
+	case <-process.sent:
+		// l2_b has picked up the hash
+		job.setStatus(JobStatusRunning)
+	case <-process.done:
+	case <-ctx.Done():
+	}
+
+	job.setRes(sv.wait(ctx, process), milestone)
+
+	time.AfterFunc(job_ttl, func() {
+		sv.job_map.Delete(job.Res().Id)
+	})
+}
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"encoding/json"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func TestJobSetRes(t *testing.T) {
+	latency, err := NewLatencyV(chain.RootL2Hash{
+		L2Hash: chain.L2Hash{ChainId: "10", Hash: "0xa"},
+		Href:   "https://etherscan.io/tx/0xbatch",
+		Start:  1_000_000,
+	}, chain.RootTx{RootEnd: 5_000_000, RootFinalized: 9_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		milestone   chain.Milestone
+		want_status JobStatus
+		want        string
+		want_code   status.ErrCode
+	}{
+		{milestone: chain.MilestoneL1Posted, want_status: JobStatusDone, want: "5000000"},
+		{milestone: chain.MilestoneL1Finalized, want_status: JobStatusDone, want: "9000000"},
+		{milestone: chain.MilestoneStateFinalized, want_status: JobStatusFailed, want_code: status.ErrCodeMilestonePending},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.milestone), func(t *testing.T) {
+			job := &Job{mu: &sync.Mutex{}}
+			job.setRes(latency, tt.milestone)
+
+			got := job.Res()
+			if got.Status != tt.want_status || got.ErrCode != tt.want_code {
+				t.Fatalf("got %+v, want %s %s", got, tt.want_status, tt.want_code)
+			}
+			if tt.want != "" && (got.Result == nil || got.Result.RootEnd != tt.want) {
+				t.Fatalf("got result %+v, want root_end %s", got.Result, tt.want)
+			}
+		})
+	}
+}
+
+func TestJobsPostInvalidMilestone(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"from_chain":"10","hash":"0xa","milestone":"l1_final"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+
+	sv := &Server{}
+	err := sv.jobs_POST(e.NewContext(req, rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := ErrRes{}
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	if rec.Code != http.StatusBadRequest || res.Code != status.ErrCodeInvalidRequest {
+		t.Fatalf("got %d %+v, want %d %s", rec.Code, res, http.StatusBadRequest, status.ErrCodeInvalidRequest)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/source"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+type LatencyRes struct {
+	FromChain chain.ChainId   `json:"from_chain"`
+	ToChain   chain.ChainId   `json:"to_chain"`
+	Hash      string          `json:"hash"`
+	Status    status.TxStatus `json:"status"`
+	// unix ms
+	L2Timestamp int64 `json:"l2_timestamp"`
+	// L1 tx the L2 tx was batched in
+	L1BatchHash string `json:"l1_batch_hash"`
+	L1BatchUrl  string `json:"l1_batch_url"`
+	// unix ms
+	L1Timestamp int64 `json:"l1_timestamp"`
+	// l2_timestamp -> milestone
+	LatencyMs int64           `json:"latency_ms"`
+	Milestone chain.Milestone `json:"milestone"`
+	// Milestones reached or scheduled, unix ms
+	Milestones map[chain.Milestone]int64 `json:"milestones"`
+	// Claim of the L2 state covering the tx, e.g. the OP dispute game
+	Settlement *chain.Settlement `json:"settlement,omitempty"`
+	// L3s & deeper, each batch down to the root chain. to_chain & l1_* are the first hop
+	Hops []chain.Hop `json:"hops,omitempty"`
+	// l2_timestamp -> root_posted, 0 while a hop is pending
+	TotalLatencyMs int64 `json:"total_latency_ms,omitempty"`
+	// Whether the batch was posted as calldata or in EIP-4844 blobs
+	PostingMode chain.PostingMode `json:"posting_mode,omitempty"`
+	BlobCount   int               `json:"blob_count"`
+	BlobHashes  []string          `json:"blob_hashes,omitempty"`
+}
+type LatencyV struct {
+	HasCode
+	LatencyRes
+}
+
+func NewLatencyV(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (LatencyV, error) {
+	l1_batch_hash, l1_batch_hash_err := source.HrefHash(root_l2_hash.Href)
+	if l1_batch_hash_err != nil {
+		return LatencyV{}, status.NewErr(status.ErrCodeScrapeFailed, l1_batch_hash_err)
+	}
+	to_chain_id, _ := chain.MapChainIdRoot(root_l2_hash.ChainId)
+	var settlement *chain.Settlement
+	if root_tx.Settlement.Id != "" {
+		settlement = &root_tx.Settlement
+	}
+	var total_latency_ms int64
+	if root_tx.HopsEnd() != 0 {
+		total_latency_ms = root_tx.HopsEnd() - root_l2_hash.Start
+	}
+	return LatencyV{
+		HasCode: HasCode{
+			Code: http.StatusOK,
+		},
+		LatencyRes: LatencyRes{
+			FromChain:      root_l2_hash.ChainId,
+			ToChain:        to_chain_id,
+			Hash:           root_l2_hash.Hash,
+			Status:         status.TxStatusL1Included,
+			L2Timestamp:    root_l2_hash.Start,
+			L1BatchHash:    l1_batch_hash,
+			L1BatchUrl:     root_l2_hash.Href,
+			L1Timestamp:    root_tx.RootEnd,
+			LatencyMs:      root_tx.RootEnd - root_l2_hash.Start,
+			Milestone:      chain.MilestoneL1Posted,
+			Milestones:     root_tx.Milestones(root_l2_hash.ChainId, root_l2_hash.Start),
+			Settlement:     settlement,
+			Hops:           root_tx.HopsOf(root_l2_hash),
+			TotalLatencyMs: total_latency_ms,
+			PostingMode:    root_tx.PostingMode,
+			BlobCount:      len(root_tx.BlobHashes),
+			BlobHashes:     root_tx.BlobHashes,
+		},
+	}, nil
+}
+
+// latency measured to milestone, milestone_pending until it is reached
+func (latency LatencyV) at(milestone chain.Milestone) any {
+	ts, ts_exists := latency.Milestones[milestone]
+	if !ts_exists {
+		return NewHasErr(status.NewErr(status.ErrCodeMilestonePending, fmt.Errorf("Milestone %s not reached yet", milestone)))
+	}
+	if ts > time.Now().UnixMilli() {
+		return NewHasErr(status.NewErr(status.ErrCodeMilestonePending, fmt.Errorf("Milestone %s not reached until %d", milestone, ts)))
+	}
+	latency.Milestone = milestone
+	latency.LatencyMs = ts - latency.L2Timestamp
+	return latency
+}
+
+func (sv *Server) latency_GET(c echo.Context) error {
+	return sv.resolveQuery(c, latencyRes)
+}
+
+func latencyRes(latency LatencyRes) any {
+	return latency
+}
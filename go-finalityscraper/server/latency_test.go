@@ -0,0 +1,53 @@
+package server
+
+import (
+	"encoding/json"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"strings"
+	"testing"
+)
+
+func TestNewLatencyVChains(t *testing.T) {
+	tests := []struct {
+		chain_id chain.ChainId
+		want     string
+	}{
+		{chain_id: "10", want: `"from_chain":"10","to_chain":"1"`},
+		// Registry ids need not be numbers
+		{chain_id: "SN_MAIN", want: `"from_chain":"SN_MAIN","to_chain":"1"`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.chain_id), func(t *testing.T) {
+			latency, err := NewLatencyV(chain.RootL2Hash{
+				L2Hash: chain.L2Hash{ChainId: tt.chain_id, Hash: "0xa"},
+				Href:   "https://etherscan.io/tx/0xbatch",
+				Start:  1_000,
+			}, chain.RootTx{RootEnd: 5_000})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := json.Marshal(latency.LatencyRes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(b); !strings.HasPrefix(got, "{"+tt.want) {
+				t.Fatalf("got %s, want it to start with %s", got, tt.want)
+			}
+			if latency.L1BatchHash != "0xbatch" || latency.LatencyMs != 4_000 {
+				t.Fatalf("got %+v", latency.LatencyRes)
+			}
+		})
+	}
+}
+
+func TestNewLatencyVInvalidHref(t *testing.T) {
+	_, err := NewLatencyV(chain.RootL2Hash{
+		L2Hash: chain.L2Hash{ChainId: "10", Hash: "0xa"},
+		Href:   "https://[etherscan.io/tx/0xbatch",
+	}, chain.RootTx{})
+	if status.CodeOf(err) != status.ErrCodeScrapeFailed {
+		t.Fatalf("got error %v, want %s", err, status.ErrCodeScrapeFailed)
+	}
+}
@@ -1,53 +1,102 @@
 package server
 
 import (
+	"context"
+	"fmt"
 	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo"
 )
 
+type RootEndReq struct {
+	FromChain string `json:"from_chain"`
+	Hash      string `json:"hash"`
+	// Timestamp returned as root_end, l1_posted if empty
+	Milestone chain.Milestone `json:"milestone"`
+}
+
 type RootEndRes struct {
 	RootEnd string `json:"root_end"`
 }
-type RootEndV struct {
-	HasCode
-	RootEndRes
-}
 
 func (sv *Server) root_end_GET(c echo.Context) error {
+	return sv.resolveQuery(c, rootEndRes)
+}
+
+// Resolves ?from_chain=&hash= within ?timeout=, responds with mapLatency of the result at ?milestone=
+func (sv *Server) resolveQuery(c echo.Context, mapLatency func(LatencyRes) any) error {
 	from_chain_id := c.QueryParam("from_chain")
 	from_chain_url, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(from_chain_id))
 	if from_chain_url_err != nil {
-		return c.JSON(http.StatusBadRequest, ErrRes{
-			Err: from_chain_url_err.Error(),
-		})
+		return errJSON(c, status.CodeOf(from_chain_url_err), from_chain_url_err)
 	}
 
 	hash := c.QueryParam("hash")
 
-	res_chan := sv.initResChan(hash)
-	sv.l2_hash <- chain.L2Hash{
+	milestone, milestone_err := chain.ParseMilestone(c.QueryParam("milestone"))
+	if milestone_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, milestone_err)
+	}
+
+	timeout, timeout_err := sv.requestTimeout(c.QueryParam("timeout"))
+	if timeout_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, timeout_err)
+	}
+	ctx, cancel := context.WithTimeout(c.Request().Context(), timeout)
+	defer cancel()
+
+	res := sv.wait(ctx, sv.dispatch(chain.L2Hash{
+		ChainId:  chain.ChainId(from_chain_id),
 		ChainUrl: from_chain_url,
 		Hash:     hash,
+	}))
+
+	code, v := mapRes(res, milestone, mapLatency)
+	return c.JSON(code, v)
+}
+
+// Optional ?timeout=, capped at ROOT_END_TIMEOUT
+func (sv *Server) requestTimeout(timeout_str string) (time.Duration, error) {
+	if timeout_str == "" {
+		return sv.root_end_timeout, nil
+	}
+	timeout, timeout_err := time.ParseDuration(timeout_str)
+	if timeout_err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("Invalid timeout: %s", timeout_str)
+	}
+	if timeout > sv.root_end_timeout {
+		return sv.root_end_timeout, nil
+	}
+	return timeout, nil
+}
+
+// Timestamp of the milestone
+func rootEndRes(latency LatencyRes) any {
+	return RootEndRes{
+		RootEnd: strconv.FormatInt(latency.Milestones[latency.Milestone], 10),
+	}
+}
+
+// Maps a result delivered through SetChanRes to its (status code, response body) at milestone
+func mapRes(res any, milestone chain.Milestone, mapLatency func(LatencyRes) any) (int, any) {
+	latency, latency_ok := res.(LatencyV)
+	if latency_ok {
+		res = latency.at(milestone)
 	}
-	res := <-res_chan
 
 	has_err, has_err_ok := res.(HasErr)
 	if has_err_ok {
-		return c.JSON(has_err.Code, ErrRes{
-			Err: has_err.Err,
-		})
+		return has_err.HasCode.Code, has_err.ErrRes
 	}
 
-	root_end, root_end_ok := res.(RootEndV)
-	if root_end_ok {
-		return c.JSON(root_end.Code, RootEndRes{
-			RootEnd: root_end.RootEnd,
-		})
+	latency, latency_ok = res.(LatencyV)
+	if latency_ok {
+		return latency.Code, mapLatency(latency.LatencyRes)
 	}
 
-	return c.JSON(500, ErrRes{
-		Err: "Unknown error",
-	})
+	return http.StatusInternalServerError, NewErrRes(status.ErrCodeUnknown, fmt.Errorf("Unknown error"))
 }
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/chans"
+	"go-finalityscraper/common/events"
+	"go-finalityscraper/common/status"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+// Comment lines keeping idle streams open through proxies
+const stream_keepalive = 15 * time.Second
+
+const (
+	// Last event of a hash stream, the /latency record
+	StreamEventResult = "result"
+	// Last event of a failed hash stream, an ErrRes
+	StreamEventError = "error"
+)
+
+// Streams events of ?hash= until it resolves within ?timeout=,
+// or every event (of ?from_chain=) until the client leaves
+func (sv *Server) stream_GET(c echo.Context) error {
+	from_chain_id := chain.ChainId(c.QueryParam("from_chain"))
+	hash := c.QueryParam("hash")
+
+	var from_chain_url chain.ChainUrl
+	if from_chain_id != "" || hash != "" {
+		url, url_err := chain.MapChainIdUrl(from_chain_id)
+		if url_err != nil {
+			return errJSON(c, status.CodeOf(url_err), url_err)
+		}
+		from_chain_url = url
+	}
+
+	milestone, milestone_err := chain.ParseMilestone(c.QueryParam("milestone"))
+	if milestone_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, milestone_err)
+	}
+
+	ctx := c.Request().Context()
+	if hash != "" {
+		timeout, timeout_err := sv.requestTimeout(c.QueryParam("timeout"))
+		if timeout_err != nil {
+			return errJSON(c, status.ErrCodeInvalidRequest, timeout_err)
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Subscribed before dispatching, so no event is missed
+	sub, unsubscribe := sv.bus.Subscribe()
+	defer unsubscribe()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	// nil for every event
+	var done chans.DoneChan
+	var process *resProcess
+	if hash != "" {
+		process = sv.dispatch(chain.L2Hash{
+			ChainId:  from_chain_id,
+			ChainUrl: from_chain_url,
+			Hash:     hash,
+		})
+		done = process.done
+		// Released once, whichever way the stream ends
+		defer sv.releaseRes(process)
+	}
+
+	keepalive := time.NewTicker(stream_keepalive)
+	defer keepalive.Stop()
+
+	writeEvent := func(e events.Event) error {
+		if !e.Matches(from_chain_id, hash) {
+			return nil
+		}
+		return writeStreamEvent(res, string(e.Type), e)
+	}
+
+	for {
+		select {
+		case e := <-sub:
+			err := writeEvent(e)
+			if err != nil {
+				return nil
+			}
+
+		case <-keepalive.C:
+			_, err := fmt.Fprint(res, ": keepalive\n\n")
+			if err != nil {
+				return nil
+			}
+			res.Flush()
+
+		case <-done:
+			// Events published before the result come first
+			for len(sub) > 0 {
+				writeEvent(<-sub)
+			}
+			return writeStreamResult(res, process.result, milestone)
+
+		case <-ctx.Done():
+			if process == nil {
+				return nil
+			}
+			return writeStreamResult(res, timedOut(ctx), milestone)
+		}
+	}
+}
+
+func writeStreamResult(res *echo.Response, v any, milestone chain.Milestone) error {
+	_, body := mapRes(v, milestone, latencyRes)
+	_, is_err := body.(ErrRes)
+	if is_err {
+		return writeStreamEvent(res, StreamEventError, body)
+	}
+	return writeStreamEvent(res, StreamEventResult, body)
+}
+
+func writeStreamEvent(res *echo.Response, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", name, data)
+	if err != nil {
+		return err
+	}
+	res.Flush()
+	return nil
+}
@@ -0,0 +1,383 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const bucket_watches = "watches"
+
+// How long a delivered or failed watch stays queryable
+const watch_ttl = 24 * time.Hour
+
+const webhook_timeout = 10 * time.Second
+
+// Not public on top of what net.IP tells, shared (CGNAT), "this network" & benchmarking ranges
+var blocked_nets = mustParseCIDRs("0.0.0.0/8", "100.64.0.0/10", "198.18.0.0/15")
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := []*net.IPNet{}
+	for _, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, n)
+	}
+	return nets
+}
+
+// Whether callbacks may reach ip, not the host's own network or cloud metadata
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, n := range blocked_nets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Checks the address actually dialed, after DNS & for every redirect, so hostnames
+// cannot point callbacks at internal addresses, even if they resolve differently by then
+// (DNS rebinding)
+func dialPublic(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("Callback address not allowed: %s", host)
+	}
+	return nil
+}
+
+const webhook_max_redirects = 3
+
+// No proxy from the environment, it would be the address checked
+var webhook_client = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: webhook_timeout,
+			Control: dialPublic,
+		}).DialContext,
+		TLSHandshakeTimeout: webhook_timeout,
+	},
+	CheckRedirect: checkRedirect,
+}
+
+// Redirects are held to callback_url rules too, their addresses are checked when dialed
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= webhook_max_redirects {
+		return fmt.Errorf("Stopped after %d redirects", len(via))
+	}
+	_, err := parseCallbackUrl(req.URL.String())
+	return err
+}
+
+// http(s) with a host, rejecting literal non-public IPs & localhost early, others at dial time
+func parseCallbackUrl(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return nil, fmt.Errorf("Invalid callback_url: %s", s)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, fmt.Errorf("Invalid callback_url, not a public address: %s", s)
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && !isPublicIP(ip) {
+		return nil, fmt.Errorf("Invalid callback_url, not a public address: %s", s)
+	}
+	return u, nil
+}
+
+// Watches need WATCH_SECRET to sign webhooks
+func (sv *Server) watchEnabled(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if sv.watch_secret == "" {
+			return errJSON(c, status.ErrCodeDisabled, fmt.Errorf("/watch is disabled, WATCH_SECRET is not set"))
+		}
+		return next(c)
+	}
+}
+
+// Signature of the webhook body, hex HMAC-SHA256 keyed with WATCH_SECRET
+const HeaderSignature = "X-Signature-256"
+const HeaderWatchId = "X-Watch-Id"
+
+type WatchStatus string
+
+const (
+	// Waiting for the L1 timestamp
+	WatchStatusWatching WatchStatus = "watching"
+	// L1 timestamp known, webhook not delivered yet
+	WatchStatusDelivering WatchStatus = "delivering"
+	WatchStatusDelivered  WatchStatus = "delivered"
+	WatchStatusFailed     WatchStatus = "failed"
+)
+
+type WatchReq struct {
+	FromChain   string `json:"from_chain"`
+	Hash        string `json:"hash"`
+	CallbackUrl string `json:"callback_url"`
+	// Delivered once the tx reaches it, l1_posted if empty
+	Milestone chain.Milestone `json:"milestone"`
+}
+
+type Watch struct {
+	Id string `json:"id"`
+	WatchReq
+	Status  WatchStatus `json:"status"`
+	Latency *LatencyRes `json:"latency,omitempty"`
+	Err     string      `json:"err,omitempty"`
+	// Webhook deliveries
+	Attempts int `json:"attempts"`
+	// unix ms
+	NextAttempt int64 `json:"next_attempt"`
+	FinishedAt  int64 `json:"finished_at,omitempty"`
+}
+
+func (sv *Server) watch_POST(c echo.Context) error {
+	req := WatchReq{}
+	bind_err := c.Bind(&req)
+	if bind_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, bind_err)
+	}
+
+	_, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(req.FromChain))
+	if from_chain_url_err != nil {
+		return errJSON(c, status.CodeOf(from_chain_url_err), from_chain_url_err)
+	}
+
+	milestone, milestone_err := chain.ParseMilestone(string(req.Milestone))
+	if milestone_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, milestone_err)
+	}
+	req.Milestone = milestone
+
+	_, callback_url_err := parseCallbackUrl(req.CallbackUrl)
+	if callback_url_err != nil {
+		return errJSON(c, status.ErrCodeInvalidRequest, callback_url_err)
+	}
+
+	id, id_err := newId()
+	if id_err != nil {
+		return errJSON(c, status.ErrCodeUnknown, id_err)
+	}
+
+	w := Watch{
+		Id:       id,
+		WatchReq: req,
+		Status:   WatchStatusWatching,
+	}
+	sv.watch_mu.Lock()
+	put_err := sv.store.Put(bucket_watches, id, w)
+	sv.watch_mu.Unlock()
+	if put_err != nil {
+		return errJSON(c, status.ErrCodeUnknown, put_err)
+	}
+
+	return c.JSON(http.StatusAccepted, w)
+}
+
+func (sv *Server) watch_GET(c echo.Context) error {
+	id := c.Param("id")
+	w := Watch{}
+	found, err := sv.store.Get(bucket_watches, id, &w)
+	if err != nil {
+		return errJSON(c, status.ErrCodeUnknown, err)
+	}
+	if !found {
+		return errJSON(c, status.ErrCodeNotFound, fmt.Errorf("Watch not found: %s", id))
+	}
+	return c.JSON(http.StatusOK, w)
+}
+
+func (sv *Server) watch_DELETE(c echo.Context) error {
+	id := c.Param("id")
+	sv.watch_mu.Lock()
+	deleted, err := sv.store.Delete(bucket_watches, id)
+	sv.watch_mu.Unlock()
+	if err != nil {
+		return errJSON(c, status.ErrCodeUnknown, err)
+	}
+	if !deleted {
+		return errJSON(c, status.ErrCodeNotFound, fmt.Errorf("Watch not found: %s", id))
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// Re-checks stored watches every WATCH_INTERVAL, so they survive restarts
+func (sv *Server) watchLoop() {
+	for {
+		sv.checkWatches()
+		time.Sleep(sv.watch_interval)
+	}
+}
+
+func (sv *Server) checkWatches() {
+	watches := []Watch{}
+	sv.store.Iter(bucket_watches, func(key string, raw []byte) bool {
+		w := Watch{}
+		err := json.Unmarshal(raw, &w)
+		if err != nil {
+			fmt.Println("Error reading watch", key+":", err)
+			return true
+		}
+		watches = append(watches, w)
+		return true
+	})
+
+	sem := make(chan struct{}, sv.batch_concurrency)
+	wg := &sync.WaitGroup{}
+	for _, w := range watches {
+		wg.Add(1)
+		go func(w Watch) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			sv.checkWatch(w)
+		}(w)
+	}
+	wg.Wait()
+}
+
+func (sv *Server) checkWatch(w Watch) {
+	now := time.Now()
+
+	switch w.Status {
+	case WatchStatusDelivered, WatchStatusFailed:
+		if now.Sub(time.UnixMilli(w.FinishedAt)) > watch_ttl {
+			sv.watch_mu.Lock()
+			sv.store.Delete(bucket_watches, w.Id)
+			sv.watch_mu.Unlock()
+		}
+		return
+
+	case WatchStatusWatching:
+		ctx, cancel := context.WithTimeout(context.Background(), sv.root_end_timeout)
+		defer cancel()
+		from_chain_url, from_chain_url_err := chain.MapChainIdUrl(chain.ChainId(w.FromChain))
+		if from_chain_url_err != nil {
+			// Chain retired since the watch was added
+			w.Status = WatchStatusFailed
+			w.Err = from_chain_url_err.Error()
+			w.FinishedAt = now.UnixMilli()
+			sv.saveWatch(w)
+			return
+		}
+		// Empty for watches stored before milestones
+		milestone, _ := chain.ParseMilestone(string(w.Milestone))
+		code, v := mapRes(sv.wait(ctx, sv.dispatch(chain.L2Hash{
+			ChainId:  chain.ChainId(w.FromChain),
+			ChainUrl: from_chain_url,
+			Hash:     w.Hash,
+		})), milestone, latencyRes)
+		switch v := v.(type) {
+		case LatencyRes:
+			w.Status = WatchStatusDelivering
+			w.Latency = &v
+			w.NextAttempt = now.UnixMilli()
+		case ErrRes:
+			if v.Retry {
+				// Not on L1 yet, check again next time
+				return
+			}
+			w.Status = WatchStatusFailed
+			w.Err = v.Err
+			w.FinishedAt = now.UnixMilli()
+		default:
+			fmt.Println("Unexpected watch result", w.Id+":", code)
+			return
+		}
+	}
+
+	if w.Status == WatchStatusDelivering && now.UnixMilli() >= w.NextAttempt {
+		deliver_err := sv.deliverWatch(w)
+		w.Attempts++
+		if deliver_err == nil {
+			w.Status = WatchStatusDelivered
+			w.Err = ""
+			w.FinishedAt = now.UnixMilli()
+		} else if w.Attempts >= sv.watch_max_attempts {
+			w.Status = WatchStatusFailed
+			w.Err = deliver_err.Error()
+			w.FinishedAt = now.UnixMilli()
+		} else {
+			w.Err = deliver_err.Error()
+			// Exponential backoff from WATCH_INTERVAL
+			w.NextAttempt = now.Add(sv.watch_interval << (w.Attempts - 1)).UnixMilli()
+		}
+	}
+
+	sv.saveWatch(w)
+}
+
+// Only saves watches that were not deleted in the meantime
+func (sv *Server) saveWatch(w Watch) {
+	sv.watch_mu.Lock()
+	defer sv.watch_mu.Unlock()
+	found, _ := sv.store.Get(bucket_watches, w.Id, &Watch{})
+	if !found {
+		return
+	}
+	err := sv.store.Put(bucket_watches, w.Id, w)
+	if err != nil {
+		fmt.Println("Error saving watch", w.Id+":", err)
+	}
+}
+
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// POSTs the latency record to the callback url, signed with WATCH_SECRET
+func (sv *Server) deliverWatch(w Watch) error {
+	body, err := json.Marshal(w.Latency)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhook_timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.CallbackUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(HeaderSignature, Sign(sv.watch_secret, body))
+	req.Header.Set(HeaderWatchId, w.Id)
+
+	res, err := webhook_client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Error delivering webhook: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("Error delivering webhook: status %d", res.StatusCode)
+	}
+	return nil
+}
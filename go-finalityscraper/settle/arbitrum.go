@@ -0,0 +1,197 @@
+package settle
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/batch"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"strconv"
+	"time"
+)
+
+// Before BoLD
+const (
+	// NodeCreated(uint64 indexed nodeNum, bytes32 indexed parentNodeHash, bytes32 indexed nodeHash, bytes32 executionHash,
+	// Assertion assertion, bytes32 afterInboxBatchAcc, bytes32 wasmModuleRoot, uint256 inboxMaxCount)
+	topic_node_created = "0x4f4caa9e67fb994e349dd35d1ad0ce23053d4323f83ce11dc817b5435031d096"
+	// NodeConfirmed(uint64 indexed nodeNum, bytes32 blockHash, bytes32 sendRoot)
+	topic_node_confirmed = "0x22ef0479a7ff660660d1c2fe35f1b632cf31675c2d9378db8cec95b00d8ffa3c"
+)
+
+// BoLD
+const (
+	// AssertionCreated(bytes32 indexed assertionHash, bytes32 indexed parentAssertionHash, AssertionInputs assertion,
+	// bytes32 afterInboxBatchAcc, uint256 inboxMaxCount, bytes32 wasmModuleRoot, uint256 requiredStake,
+	// address challengeManager, uint64 confirmPeriodBlocks)
+	topic_assertion_created = "0x901c3aee23cf4478825462caaab375c606ab83516060388344f0650340753630"
+	// AssertionConfirmed(bytes32 indexed assertionHash, bytes32 blockHash, bytes32 sendRoot)
+	topic_assertion_confirmed = "0xfc42829b29c259a7370ab56c8f69fce23b5f351a9ce151da453281993ec0090c"
+)
+
+// Data words of the created events, the structs are static so they are inlined
+const (
+	// assertion.afterState.globalState.u64Vals[0], the next batch to read
+	node_after_batch_word      = 8
+	assertion_after_batch_word = 15
+	confirm_period_word        = 24
+)
+
+// Confirm periods count Ethereum blocks, also on L3s: block.number is the L1 block on Arbitrum
+const l1_block_time = 12 * time.Second
+
+// Finds the first node (or assertion) reading past the batch of the tx, and when it was confirmed.
+// Withdrawals can be executed once it is, so it is also the finalization time.
+// Every tx of a batch is covered by the same claim.
+type ArbitrumRollup struct {
+	root   *rpc.Eth
+	scan   scan
+	rollup chain.Rollup
+	// By sequence number
+	settled *settled
+}
+
+func NewArbitrumRollup(root rpc.Caller, root_block_time time.Duration, rollup chain.Rollup) *ArbitrumRollup {
+	return &ArbitrumRollup{
+		root:    rpc.NewEth(root),
+		scan:    newScan(root_block_time),
+		rollup:  rollup,
+		settled: newSettled(),
+	}
+}
+
+// A created node or assertion
+type arbitrumClaim struct {
+	settlement    chain.Settlement
+	created_block uint64
+	// L1 blocks
+	confirm_period uint64
+	confirm_filter rpc.LogFilter
+}
+
+func (r *ArbitrumRollup) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	sequence_number, sequence_number_err := r.sequenceNumber(ctx, root_l2_hash)
+	if sequence_number_err != nil {
+		return chain.Settlement{}, sequence_number_err
+	}
+	settlement, settlement_exists := r.settled.get(sequence_number)
+	if settlement_exists {
+		return settlement, nil
+	}
+	from, from_err := rootBlock(ctx, r.root, root_tx)
+	if from_err != nil {
+		return chain.Settlement{}, from_err
+	}
+
+	var claim *arbitrumClaim
+	_, scan_err := scanLogs(ctx, r.root, r.scan, rpc.LogFilter{
+		Address: r.rollup.Address,
+		Topics:  []any{[]string{topic_node_created, topic_assertion_created}},
+	}, from, func(log rpc.Log) (bool, error) {
+		log_claim, log_claim_err := r.parseClaim(log, sequence_number)
+		claim = log_claim
+		return claim != nil, log_claim_err
+	})
+	if scan_err != nil || claim == nil {
+		return chain.Settlement{}, scan_err
+	}
+
+	proposed, proposed_err := blockTime(ctx, r.root, claim.created_block)
+	if proposed_err != nil {
+		return chain.Settlement{}, proposed_err
+	}
+	claim.settlement.Proposed = proposed
+
+	// Not confirmable before the confirm period
+	confirmable := claim.created_block + chain.BlocksIn(time.Duration(claim.confirm_period)*l1_block_time, r.scan.block_time)
+	_, confirm_err := scanLogs(ctx, r.root, r.scan, claim.confirm_filter, confirmable, func(log rpc.Log) (bool, error) {
+		confirmed, confirmed_err := blockTime(ctx, r.root, uint64(log.BlockNumber))
+		claim.settlement.Resolved = confirmed
+		claim.settlement.Finalized = confirmed
+		claim.settlement.ResolvedTx = log.TxHash
+		claim.settlement.FinalizedTx = log.TxHash
+		return true, confirmed_err
+	})
+	if confirm_err != nil {
+		return chain.Settlement{}, confirm_err
+	}
+	r.settled.set(sequence_number, claim.settlement)
+	return claim.settlement, nil
+}
+
+// Sequence number of the batch, from the input data of the batch tx
+func (r *ArbitrumRollup) sequenceNumber(ctx context.Context, root_l2_hash chain.RootL2Hash) (uint64, error) {
+	tx_hash, tx_hash_err := hrefHash(root_l2_hash.Href)
+	if tx_hash_err != nil {
+		return 0, tx_hash_err
+	}
+	tx, tx_err := r.root.GetTransactionByHash(ctx, tx_hash)
+	if tx_err != nil {
+		return 0, tx_err
+	}
+	if tx == nil {
+		return 0, status.NewErr(status.ErrCodeL1Unconfirmed, fmt.Errorf("L1 tx not found: %s", root_l2_hash.Href))
+	}
+	b, b_err := batch.DecodeTx(root_l2_hash.ChainId, uint64(tx.Type), tx.Input, tx.BlobVersionedHashes)
+	if b_err != nil {
+		return 0, status.NewErr(status.ErrCodeScrapeFailed, b_err)
+	}
+	return b.SequenceNumber, nil
+}
+
+// Claim of a created event, nil if it does not read past the batch
+func (r *ArbitrumRollup) parseClaim(log rpc.Log, sequence_number uint64) (*arbitrumClaim, error) {
+	if len(log.Topics) < 2 {
+		return nil, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Rollup event without id: %s", log.TxHash))
+	}
+	data, data_err := abi.DecodeHex(log.Data)
+	if data_err != nil {
+		return nil, data_err
+	}
+	id := log.Topics[1]
+
+	claim := &arbitrumClaim{
+		settlement: chain.Settlement{
+			ProposedTx: log.TxHash,
+		},
+		created_block: uint64(log.BlockNumber),
+		confirm_filter: rpc.LogFilter{
+			Address: r.rollup.Address,
+		},
+	}
+	after_batch_word := node_after_batch_word
+	if log.Topics[0] == topic_assertion_created {
+		after_batch_word = assertion_after_batch_word
+		confirm_period, confirm_period_err := abi.Uint(data, confirm_period_word)
+		if confirm_period_err != nil {
+			return nil, confirm_period_err
+		}
+		claim.settlement.Id = id
+		claim.confirm_period = confirm_period
+		claim.confirm_filter.Topics = rpc.Topics(topic_assertion_confirmed, id)
+	} else {
+		topic, topic_err := abi.DecodeHex(id)
+		if topic_err != nil {
+			return nil, topic_err
+		}
+		node_num, node_num_err := abi.Uint(topic, 0)
+		if node_num_err != nil {
+			return nil, node_num_err
+		}
+		claim.settlement.Id = strconv.FormatUint(node_num, 10)
+		claim.confirm_period = r.rollup.ConfirmPeriodBlocks
+		claim.confirm_filter.Topics = rpc.Topics(topic_node_confirmed, id)
+	}
+
+	after_batch, after_batch_err := abi.Uint(data, after_batch_word)
+	if after_batch_err != nil {
+		return nil, after_batch_err
+	}
+	if after_batch <= sequence_number {
+		return nil, nil
+	}
+	return claim, nil
+}
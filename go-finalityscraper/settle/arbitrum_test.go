@@ -0,0 +1,179 @@
+package settle
+
+import (
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	test_rollup    = "0x00000000000000000000000000000000000000b0"
+	test_assertion = "0x00000000000000000000000000000000000000000000000000000000000a55e7"
+)
+
+// n words of log data, zero but for at
+func wordsAt(n int, at map[int]uint64) string {
+	words := make([]uint64, n)
+	for i, v := range at {
+		words[i] = v
+	}
+	return wordsHex(words...)
+}
+
+func TestArbitrumParseClaim(t *testing.T) {
+	r := NewArbitrumRollup(nil, 12*time.Second, chain.Rollup{Address: test_rollup, ConfirmPeriodBlocks: 45_818})
+	node_topics := []string{topic_node_created, abi.UintHex(42), abi.UintHex(0), abi.UintHex(0)}
+	assertion_topics := []string{topic_assertion_created, test_assertion, abi.UintHex(0)}
+
+	tests := []struct {
+		name string
+		log  rpc.Log
+		// nil if the claim does not read past the batch
+		want     *arbitrumClaim
+		want_err bool
+	}{
+		{
+			// Before BoLD, the next batch is word 8
+			name: "node past the batch",
+			log:  rpc.Log{Topics: node_topics, Data: wordsAt(12, map[int]uint64{8: 613}), BlockNumber: 150, TxHash: "0xcreate"},
+			want: &arbitrumClaim{
+				settlement:     chain.Settlement{Id: "42", ProposedTx: "0xcreate"},
+				created_block:  150,
+				confirm_period: 45_818,
+				confirm_filter: rpc.LogFilter{Address: test_rollup, Topics: rpc.Topics(topic_node_confirmed, abi.UintHex(42))},
+			},
+		},
+		{
+			name: "node up to the batch",
+			log:  rpc.Log{Topics: node_topics, Data: wordsAt(12, map[int]uint64{8: 612}), BlockNumber: 150},
+		},
+		{
+			// BoLD, the next batch is word 15 & the confirm period word 24
+			name: "assertion past the batch",
+			log:  rpc.Log{Topics: assertion_topics, Data: wordsAt(26, map[int]uint64{8: 1, 15: 613, 24: 50_400}), BlockNumber: 150, TxHash: "0xcreate"},
+			want: &arbitrumClaim{
+				settlement:     chain.Settlement{Id: test_assertion, ProposedTx: "0xcreate"},
+				created_block:  150,
+				confirm_period: 50_400,
+				confirm_filter: rpc.LogFilter{Address: test_rollup, Topics: rpc.Topics(topic_assertion_confirmed, test_assertion)},
+			},
+		},
+		{
+			name: "assertion up to the batch",
+			log:  rpc.Log{Topics: assertion_topics, Data: wordsAt(26, map[int]uint64{8: 613, 15: 612, 24: 50_400}), BlockNumber: 150},
+		},
+		{name: "no id", log: rpc.Log{Topics: []string{topic_node_created}, Data: wordsAt(12, nil)}, want_err: true},
+		{name: "no confirm period", log: rpc.Log{Topics: assertion_topics, Data: wordsAt(16, map[int]uint64{15: 613})}, want_err: true},
+		{name: "short data", log: rpc.Log{Topics: node_topics, Data: wordsAt(8, nil)}, want_err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.parseClaim(tt.log, 612)
+			if tt.want_err {
+				if err == nil {
+					t.Fatal("got no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (got == nil) != (tt.want == nil) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if got == nil {
+				return
+			}
+			if got.settlement != tt.want.settlement || got.created_block != tt.want.created_block ||
+				got.confirm_period != tt.want.confirm_period || got.confirm_filter.Address != tt.want.confirm_filter.Address ||
+				len(got.confirm_filter.Topics) != 2 || got.confirm_filter.Topics[1] != tt.want.confirm_filter.Topics[1] ||
+				got.confirm_filter.Topics[0] != tt.want.confirm_filter.Topics[0] {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Blob batch tx of sequence number 612, its data words start after the selector
+func testArbitrumBatchTx() map[string]any {
+	return map[string]any{
+		"hash":        "0xbatch",
+		"blockNumber": rpc.Quantity(100),
+		"type":        rpc.Quantity(3),
+		"input":       "0x3e5aa082" + strings.TrimPrefix(wordsHex(612, 0, 0, 186_000_000, 186_000_100), "0x"),
+	}
+}
+
+func TestArbitrumRollupSettle(t *testing.T) {
+	tests := []struct {
+		name            string
+		root_block_time time.Duration
+		// First block read for the confirmation, created + the confirm period in root blocks
+		want_confirmable uint64
+	}{
+		{name: "on Ethereum", root_block_time: 12 * time.Second, want_confirmable: 150 + 100},
+		// Confirm periods count Ethereum blocks, also on L3s
+		{name: "on Arbitrum One", root_block_time: 250 * time.Millisecond, want_confirmable: 150 + 4_800},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &fakeRoot{
+				head: 10_000,
+				logs: []rpc.Log{
+					{
+						Address:     test_rollup,
+						Topics:      []string{topic_assertion_created, test_assertion, abi.UintHex(0)},
+						Data:        wordsAt(26, map[int]uint64{15: 613, 24: 100}),
+						BlockNumber: 150,
+						TxHash:      "0xcreate",
+					},
+					// Of another assertion
+					{Address: test_rollup, Topics: []string{topic_assertion_confirmed, abi.UintHex(1)}, BlockNumber: 6_000, TxHash: "0xother"},
+					{Address: test_rollup, Topics: []string{topic_assertion_confirmed, test_assertion}, BlockNumber: 7_000, TxHash: "0xconfirm"},
+				},
+			}
+			handlers := root.handlers()
+			handlers["eth_getTransactionByHash"] = func(params []any) any {
+				return testArbitrumBatchTx()
+			}
+			caller := newFakeCaller(t, handlers)
+			r := NewArbitrumRollup(caller, tt.root_block_time, chain.Rollup{Address: test_rollup})
+
+			root_l2_hash := testRootL2Hash(0)
+			root_l2_hash.ChainId = "42161"
+			want := chain.Settlement{
+				Id:          test_assertion,
+				Proposed:    testBlockTime(150),
+				Resolved:    testBlockTime(7_000),
+				Finalized:   testBlockTime(7_000),
+				ProposedTx:  "0xcreate",
+				ResolvedTx:  "0xconfirm",
+				FinalizedTx: "0xconfirm",
+			}
+			got, err := r.Settle(root_l2_hash, chain.RootTx{RootBlock: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("got %+v, want %+v", got, want)
+			}
+			// The claim is found in the first window
+			if len(root.windows) < 2 || root.windows[1][0] != tt.want_confirmable {
+				t.Fatalf("got scans %v, want the confirmation read from block %d", root.windows, tt.want_confirmable)
+			}
+
+			// Txs of the same batch are covered by the same claim, kept once confirmed
+			scans := len(root.windows)
+			got, err = r.Settle(root_l2_hash, chain.RootTx{RootBlock: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want || len(root.windows) != scans {
+				t.Fatalf("got %+v after %d more scans, want %+v kept", got, len(root.windows)-scans, want)
+			}
+		})
+	}
+}
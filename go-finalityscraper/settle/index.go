@@ -0,0 +1,170 @@
+package settle
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"net/url"
+	"path"
+	"sync"
+	"time"
+)
+
+// Reads how the L2 state covering a tx is settled on the root chain, after root_b read
+// its batch. Called per tx, as txs of one batch may be covered by different claims.
+type Settler interface {
+	// Fields stay zero until reached
+	Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error)
+}
+
+// Most settlements kept by a settled, it starts over once full
+const settled_max = 10_000
+
+// Settlements by L2 block, batch number, ..., kept once finalized, when they no longer change
+type settled struct {
+	mu *sync.Mutex
+	m  map[uint64]chain.Settlement
+}
+
+func newSettled() *settled {
+	return &settled{
+		mu: &sync.Mutex{},
+		m:  map[uint64]chain.Settlement{},
+	}
+}
+
+func (s *settled) get(key uint64) (chain.Settlement, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settlement, settlement_exists := s.m[key]
+	return settlement, settlement_exists
+}
+
+// Only keeps finalized settlements
+func (s *settled) set(key uint64, settlement chain.Settlement) {
+	if settlement.Finalized == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.m) >= settled_max {
+		s.m = map[uint64]chain.Settlement{}
+	}
+	s.m[key] = settlement
+}
+
+// First word returned by the getter selector of to
+func callUint(ctx context.Context, root *rpc.Eth, to string, selector string) (uint64, error) {
+	result, result_err := root.CallContract(ctx, to, selector)
+	if result_err != nil {
+		return 0, result_err
+	}
+	data, data_err := abi.DecodeHex(result)
+	if data_err != nil {
+		return 0, data_err
+	}
+	return abi.Uint(data, 0)
+}
+
+// uint64 of an indexed log topic
+func topicUint(topic string) (uint64, error) {
+	word, word_err := abi.DecodeHex(topic)
+	if word_err != nil {
+		return 0, word_err
+	}
+	return abi.Uint(word, 0)
+}
+
+// Time per eth_getLogs, 1000 Ethereum blocks
+const scan_step_time = 200 * time.Minute
+
+// Blocks per eth_getLogs at most, a common range limit of providers
+const scan_step_max = 10_000
+
+// Claims are made about hourly, a scan gives up after about a week
+const scan_max_time = 7 * 24 * time.Hour
+
+// Log scans in blocks of the root chain, which are not 12s everywhere
+type scan struct {
+	block_time time.Duration
+	// Blocks per eth_getLogs
+	step uint64
+	// Blocks scanned at most
+	max uint64
+}
+
+// block_time: avg_block_time of the root chain
+func newScan(block_time time.Duration) scan {
+	return scan{
+		block_time: block_time,
+		step:       min(chain.BlocksIn(scan_step_time, block_time), scan_step_max),
+		max:        chain.BlocksIn(scan_max_time, block_time),
+	}
+}
+
+// Calls fn with the logs of filter from from on, until it returns true or the scan
+// reaches head or s.max blocks. Whether fn returned true.
+func scanLogs(ctx context.Context, root *rpc.Eth, s scan, filter rpc.LogFilter, from uint64, fn func(log rpc.Log) (bool, error)) (bool, error) {
+	head, head_err := root.BlockNumber(ctx)
+	if head_err != nil {
+		return false, head_err
+	}
+	end := min(head, from+s.max)
+
+	for start := from; start <= end; start += s.step {
+		filter.FromBlock = rpc.Quantity(start)
+		filter.ToBlock = rpc.Quantity(min(start+s.step-1, end))
+		logs, logs_err := root.GetLogs(ctx, filter)
+		if logs_err != nil {
+			return false, logs_err
+		}
+		for _, log := range logs {
+			found, found_err := fn(log)
+			if found_err != nil || found {
+				return found, found_err
+			}
+		}
+	}
+	return false, nil
+}
+
+// unix ms of an L1 block
+func blockTime(ctx context.Context, root *rpc.Eth, number uint64) (int64, error) {
+	block, block_err := root.GetBlockByNumber(ctx, number, false)
+	if block_err != nil {
+		return 0, block_err
+	}
+	if block == nil {
+		return 0, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L1 block not found: %d", number))
+	}
+	return block.TimeMs(), nil
+}
+
+// unix ms of the L1 block of a tx, 0 while it is pending
+func txTime(ctx context.Context, root *rpc.Eth, hash string) (int64, error) {
+	receipt, receipt_err := root.GetTransactionReceipt(ctx, hash)
+	if receipt_err != nil || receipt == nil {
+		return 0, receipt_err
+	}
+	return blockTime(ctx, root, uint64(receipt.BlockNumber))
+}
+
+// L1 block of the batch tx, found by its timestamp if the source did not read it
+func rootBlock(ctx context.Context, root *rpc.Eth, root_tx chain.RootTx) (uint64, error) {
+	if root_tx.RootBlock != 0 {
+		return root_tx.RootBlock, nil
+	}
+	return root.BlockAtTime(ctx, root_tx.RootEnd)
+}
+
+// Tx hash of the explorer href of a batch tx
+func hrefHash(href string) (string, error) {
+	u, u_err := url.Parse(href)
+	if u_err != nil {
+		return "", status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("Invalid href %s: %w", href, u_err))
+	}
+	return path.Base(u.Path), nil
+}
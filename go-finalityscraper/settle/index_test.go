@@ -0,0 +1,288 @@
+package settle
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Fixtures of a root chain, block n is at test_genesis_time + 12s * n
+
+const test_genesis_time = 1_700_000_000
+
+func testBlockTime(n uint64) int64 {
+	return (test_genesis_time + 12*int64(n)) * 1000
+}
+
+// Results of an rpc.Caller by method, an error to fail the call
+type fakeHandlers map[string]func(params []any) any
+
+// rpc.Caller without a node, results go through JSON as a node's would
+type fakeCaller struct {
+	t        *testing.T
+	handlers fakeHandlers
+	// Calls per method
+	calls map[string]int
+}
+
+func newFakeCaller(t *testing.T, handlers fakeHandlers) *fakeCaller {
+	return &fakeCaller{
+		t:        t,
+		handlers: handlers,
+		calls:    map[string]int{},
+	}
+}
+
+func (f *fakeCaller) Call(ctx context.Context, result any, method string, params ...any) error {
+	f.calls[method]++
+	handler, handler_exists := f.handlers[method]
+	if !handler_exists {
+		f.t.Errorf("Unexpected call %s", method)
+		return fmt.Errorf("Unexpected call %s", method)
+	}
+	v := handler(params)
+	err, is_err := v.(error)
+	if is_err {
+		return err
+	}
+	raw, raw_err := json.Marshal(v)
+	if raw_err != nil {
+		return raw_err
+	}
+	return rpc.Decode(raw, result)
+}
+
+// A root chain at head, with logs & eth_call results by "<to> <data>".
+// Calls without a result return "0x", as calls of an address without code do.
+type fakeRoot struct {
+	head  uint64
+	logs  []rpc.Log
+	calls map[string]string
+	// [from, to] of each eth_getLogs, in order
+	windows [][2]uint64
+}
+
+func (root *fakeRoot) handlers() fakeHandlers {
+	return fakeHandlers{
+		"eth_blockNumber": func(params []any) any {
+			return rpc.Quantity(root.head)
+		},
+		"eth_getBlockByNumber": func(params []any) any {
+			n, n_err := rpc.ParseQuantity(params[0].(string))
+			if n_err != nil || n > root.head {
+				return nil
+			}
+			return map[string]any{"number": rpc.Quantity(n), "timestamp": rpc.Quantity(testBlockTime(n) / 1000)}
+		},
+		"eth_getLogs": func(params []any) any {
+			filter := params[0].(rpc.LogFilter)
+			root.windows = append(root.windows, [2]uint64{uint64(filter.FromBlock), uint64(filter.ToBlock)})
+			logs := []rpc.Log{}
+			for _, log := range root.logs {
+				if log.BlockNumber >= filter.FromBlock && log.BlockNumber <= filter.ToBlock && matchesFilter(log, filter) {
+					logs = append(logs, log)
+				}
+			}
+			return logs
+		},
+		"eth_call": func(params []any) any {
+			msg := params[0].(rpc.CallMsg)
+			result, result_exists := root.calls[strings.ToLower(msg.To)+" "+msg.Data]
+			if !result_exists {
+				return "0x"
+			}
+			return result
+		},
+	}
+}
+
+func matchesFilter(log rpc.Log, filter rpc.LogFilter) bool {
+	if filter.Address != "" && !strings.EqualFold(log.Address, filter.Address) {
+		return false
+	}
+	for i, topic := range filter.Topics {
+		if topic == nil {
+			continue
+		}
+		if i >= len(log.Topics) {
+			return false
+		}
+		switch topic := topic.(type) {
+		case string:
+			if log.Topics[i] != topic {
+				return false
+			}
+		case []string:
+			any_match := false
+			for _, t := range topic {
+				any_match = any_match || log.Topics[i] == t
+			}
+			if !any_match {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// "0x" prefixed words, e.g. log data & call results
+func wordsHex(vs ...uint64) string {
+	s := "0x"
+	for _, v := range vs {
+		s += strings.TrimPrefix(abi.UintHex(v), "0x")
+	}
+	return s
+}
+
+func TestNewScan(t *testing.T) {
+	tests := []struct {
+		name       string
+		block_time time.Duration
+		want       scan
+	}{
+		{name: "Ethereum", block_time: 12 * time.Second, want: scan{block_time: 12 * time.Second, step: 1_000, max: 50_400}},
+		{name: "OP Stack", block_time: 2 * time.Second, want: scan{block_time: 2 * time.Second, step: 6_000, max: 302_400}},
+		// Steps stop at the range limit of providers
+		{name: "BSC", block_time: 750 * time.Millisecond, want: scan{block_time: 750 * time.Millisecond, step: 10_000, max: 806_400}},
+		{name: "Arbitrum One", block_time: 250 * time.Millisecond, want: scan{block_time: 250 * time.Millisecond, step: 10_000, max: 2_419_200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newScan(tt.block_time)
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanLogs(t *testing.T) {
+	log := func(block uint64) rpc.Log {
+		return rpc.Log{BlockNumber: rpc.Quantity(block), TxHash: fmt.Sprintf("0x%d", block)}
+	}
+	tests := []struct {
+		name string
+		head uint64
+		from uint64
+		s    scan
+		logs []rpc.Log
+		// Block of the log found, 0 if none
+		find         uint64
+		want_found   bool
+		want_windows [][2]uint64
+	}{
+		{
+			name:         "up to head",
+			head:         125,
+			from:         100,
+			s:            scan{step: 10, max: 1_000},
+			want_windows: [][2]uint64{{100, 109}, {110, 119}, {120, 125}},
+		},
+		{
+			name:         "up to max",
+			head:         1_000,
+			from:         100,
+			s:            scan{step: 10, max: 25},
+			want_windows: [][2]uint64{{100, 109}, {110, 119}, {120, 125}},
+		},
+		{
+			name:         "stops at the log found",
+			head:         1_000,
+			from:         100,
+			s:            scan{step: 10, max: 1_000},
+			logs:         []rpc.Log{log(104), log(113), log(117)},
+			find:         113,
+			want_found:   true,
+			want_windows: [][2]uint64{{100, 109}, {110, 119}},
+		},
+		{
+			name:         "from past head",
+			head:         99,
+			from:         100,
+			s:            scan{step: 10, max: 1_000},
+			want_windows: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &fakeRoot{head: tt.head, logs: tt.logs}
+			eth := rpc.NewEth(newFakeCaller(t, root.handlers()))
+
+			seen := []uint64{}
+			found, err := scanLogs(context.Background(), eth, tt.s, rpc.LogFilter{}, tt.from, func(log rpc.Log) (bool, error) {
+				seen = append(seen, uint64(log.BlockNumber))
+				return uint64(log.BlockNumber) == tt.find, nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if found != tt.want_found {
+				t.Fatalf("got found %t, want %t", found, tt.want_found)
+			}
+			if fmt.Sprint(root.windows) != fmt.Sprint(tt.want_windows) {
+				t.Fatalf("got windows %v, want %v", root.windows, tt.want_windows)
+			}
+			if tt.find != 0 && seen[len(seen)-1] != tt.find {
+				t.Fatalf("got logs %v after the one found", seen)
+			}
+		})
+	}
+}
+
+func TestScanLogsError(t *testing.T) {
+	root := &fakeRoot{head: 1_000}
+	handlers := root.handlers()
+	handlers["eth_getLogs"] = func(params []any) any {
+		return &rpc.Error{Code: -32005, Message: "query returned more than 10000 results"}
+	}
+	eth := rpc.NewEth(newFakeCaller(t, handlers))
+
+	_, err := scanLogs(context.Background(), eth, scan{step: 10, max: 100}, rpc.LogFilter{}, 100, func(log rpc.Log) (bool, error) {
+		return true, nil
+	})
+	if err == nil {
+		t.Fatal("got no error")
+	}
+}
+
+func TestSettled(t *testing.T) {
+	s := newSettled()
+
+	// Not final yet, it may still change
+	s.set(1, chain.Settlement{Id: "1", Proposed: 1_000})
+	_, exists := s.get(1)
+	if exists {
+		t.Fatal("got a settlement not finalized")
+	}
+
+	final := chain.Settlement{Id: "1", Proposed: 1_000, Finalized: 2_000}
+	s.set(1, final)
+	got, exists := s.get(1)
+	if !exists || got != final {
+		t.Fatalf("got %+v, %t, want %+v", got, exists, final)
+	}
+
+	// Starts over once full
+	for key := uint64(2); key <= settled_max; key++ {
+		s.set(key, final)
+	}
+	_, exists = s.get(1)
+	if !exists || len(s.m) != settled_max {
+		t.Fatalf("got %d settlements, want %d", len(s.m), settled_max)
+	}
+	s.set(settled_max+1, final)
+	_, exists = s.get(1)
+	if exists || len(s.m) != 1 {
+		t.Fatalf("got %d settlements, want only the last one", len(s.m))
+	}
+	_, exists = s.get(settled_max + 1)
+	if !exists {
+		t.Fatal("got no last settlement")
+	}
+}
@@ -0,0 +1,155 @@
+package settle
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"sync"
+	"time"
+)
+
+// DisputeGameCreated(address indexed disputeProxy, uint32 indexed gameType, bytes32 indexed rootClaim)
+const topic_dispute_game_created = "0x5b565efe82411da98814f356d0e7bcb8f0219b8d970307c5afb4a6903a8b2e35"
+
+// FaultDisputeGame getters
+const (
+	selector_l2_block_number = "0x8b85902b"
+	selector_status          = "0x200d2ed2"
+	selector_resolved_at     = "0x19effeb4"
+)
+
+// GameStatus
+const (
+	game_status_in_progress = iota
+	game_status_challenger_wins
+	game_status_defender_wins
+)
+
+// Finds the first dispute game claiming an L2 block at or after the tx,
+// created after its batch was posted
+type OptimismGames struct {
+	root    *rpc.Eth
+	scan    scan
+	games   chain.DisputeGames
+	genesis chain.Genesis
+
+	mu *sync.Mutex
+	// Game -> L2 block it claims, games never change it
+	l2_blocks map[string]uint64
+	// L2 block -> finalized settlement
+	settled *settled
+}
+
+func NewOptimismGames(root rpc.Caller, root_block_time time.Duration, games chain.DisputeGames, genesis chain.Genesis) *OptimismGames {
+	return &OptimismGames{
+		root:      rpc.NewEth(root),
+		scan:      newScan(root_block_time),
+		games:     games,
+		genesis:   genesis,
+		mu:        &sync.Mutex{},
+		l2_blocks: map[string]uint64{},
+		settled:   newSettled(),
+	}
+}
+
+func (g *OptimismGames) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	l2_block := g.genesis.BlockAt(root_l2_hash.Start / 1000)
+	settlement, settlement_exists := g.settled.get(l2_block)
+	if settlement_exists {
+		return settlement, nil
+	}
+
+	from, from_err := rootBlock(ctx, g.root, root_tx)
+	if from_err != nil {
+		return chain.Settlement{}, from_err
+	}
+
+	// Zero while there is no game yet
+	settlement = chain.Settlement{}
+	_, scan_err := scanLogs(ctx, g.root, g.scan, rpc.LogFilter{
+		Address: g.games.Factory,
+		Topics:  rpc.Topics(topic_dispute_game_created, "", abi.UintHex(uint64(g.games.GameType))),
+	}, from, func(log rpc.Log) (bool, error) {
+		game_settlement, game_ok, game_err := g.gameSettlement(ctx, log, l2_block)
+		if game_ok {
+			settlement = game_settlement
+		}
+		return game_ok, game_err
+	})
+	if scan_err == nil {
+		g.settled.set(l2_block, settlement)
+	}
+	return settlement, scan_err
+}
+
+// Settlement by the game created in log, false if it claims an older
+// L2 block or was lost by its proposer
+func (g *OptimismGames) gameSettlement(ctx context.Context, log rpc.Log, l2_block uint64) (chain.Settlement, bool, error) {
+	if len(log.Topics) < 2 {
+		return chain.Settlement{}, false, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("DisputeGameCreated without disputeProxy: %s", log.TxHash))
+	}
+	topic, topic_err := abi.DecodeHex(log.Topics[1])
+	if topic_err != nil {
+		return chain.Settlement{}, false, topic_err
+	}
+	game, game_err := abi.Address(topic, 0)
+	if game_err != nil {
+		return chain.Settlement{}, false, game_err
+	}
+
+	game_l2_block, game_l2_block_err := g.l2Block(ctx, game)
+	if game_l2_block_err != nil {
+		return chain.Settlement{}, false, game_l2_block_err
+	}
+	if game_l2_block < l2_block {
+		return chain.Settlement{}, false, nil
+	}
+	game_status, game_status_err := callUint(ctx, g.root, game, selector_status)
+	if game_status_err != nil {
+		return chain.Settlement{}, false, game_status_err
+	}
+	if game_status == game_status_challenger_wins {
+		return chain.Settlement{}, false, nil
+	}
+
+	proposed, proposed_err := blockTime(ctx, g.root, uint64(log.BlockNumber))
+	if proposed_err != nil {
+		return chain.Settlement{}, false, proposed_err
+	}
+	settlement := chain.Settlement{
+		Id:         game,
+		Proposed:   proposed,
+		ProposedTx: log.TxHash,
+	}
+	if game_status == game_status_defender_wins {
+		resolved_at, resolved_at_err := callUint(ctx, g.root, game, selector_resolved_at)
+		if resolved_at_err != nil {
+			return chain.Settlement{}, false, resolved_at_err
+		}
+		settlement.Resolved = int64(resolved_at) * 1000
+		settlement.Finalized = settlement.Resolved + g.games.AirGap.Milliseconds()
+	}
+	return settlement, true, nil
+}
+
+func (g *OptimismGames) l2Block(ctx context.Context, game string) (uint64, error) {
+	g.mu.Lock()
+	l2_block, l2_block_exists := g.l2_blocks[game]
+	g.mu.Unlock()
+	if l2_block_exists {
+		return l2_block, nil
+	}
+
+	l2_block, l2_block_err := callUint(ctx, g.root, game, selector_l2_block_number)
+	if l2_block_err != nil {
+		return 0, l2_block_err
+	}
+	g.mu.Lock()
+	g.l2_blocks[game] = l2_block
+	g.mu.Unlock()
+	return l2_block, nil
+}
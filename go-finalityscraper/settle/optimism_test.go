@@ -0,0 +1,130 @@
+package settle
+
+import (
+	"context"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"testing"
+	"time"
+)
+
+const (
+	test_factory = "0x00000000000000000000000000000000000000fa"
+	test_game_1  = "0x00000000000000000000000000000000000000a1"
+	test_game_2  = "0x00000000000000000000000000000000000000a2"
+	test_game_3  = "0x00000000000000000000000000000000000000a3"
+)
+
+// L2 blocks every 2s from the root chain's genesis time
+var test_l2_genesis = chain.Genesis{Block: 0, Time: test_genesis_time, BlockTime: 2}
+
+// L2 tx at L2 block l2_block, batched at root block 100
+func testRootL2Hash(l2_block int64) chain.RootL2Hash {
+	return chain.RootL2Hash{
+		L2Hash: chain.L2Hash{Ctx: context.Background(), ChainId: "10", Hash: "0xa"},
+		Href:   "https://etherscan.io/tx/0xbatch",
+		Start:  (test_genesis_time + 2*l2_block) * 1000,
+	}
+}
+
+func gameCreated(block uint64, game string, game_type uint64) rpc.Log {
+	return rpc.Log{
+		Address:     test_factory,
+		Topics:      []string{topic_dispute_game_created, abi.AddressHex(game), abi.UintHex(game_type), abi.UintHex(0)},
+		BlockNumber: rpc.Quantity(block),
+		TxHash:      "0xcreate" + game[len(game)-2:],
+	}
+}
+
+func TestOptimismGamesSettle(t *testing.T) {
+	air_gap := 84 * time.Hour
+	tests := []struct {
+		name        string
+		game_status uint64
+		want        chain.Settlement
+		// Whether the settlement is kept, no scan on the next tx
+		want_kept bool
+	}{
+		{
+			name:        "defender wins",
+			game_status: game_status_defender_wins,
+			want: chain.Settlement{
+				Id:         test_game_3,
+				Proposed:   testBlockTime(120),
+				ProposedTx: "0xcreatea3",
+				Resolved:   1_700_400_000_000,
+				Finalized:  1_700_400_000_000 + air_gap.Milliseconds(),
+			},
+			want_kept: true,
+		},
+		{
+			name:        "in progress",
+			game_status: game_status_in_progress,
+			want: chain.Settlement{
+				Id:         test_game_3,
+				Proposed:   testBlockTime(120),
+				ProposedTx: "0xcreatea3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := &fakeRoot{
+				head: 1_000,
+				logs: []rpc.Log{
+					// Another game type
+					gameCreated(103, test_game_1, 1),
+					// Older L2 block
+					gameCreated(105, test_game_1, 0),
+					// Lost by its proposer
+					gameCreated(110, test_game_2, 0),
+					gameCreated(120, test_game_3, 0),
+				},
+				calls: map[string]string{
+					test_game_1 + " " + selector_l2_block_number: wordsHex(990),
+					test_game_2 + " " + selector_l2_block_number: wordsHex(1_100),
+					test_game_2 + " " + selector_status:          wordsHex(game_status_challenger_wins),
+					test_game_3 + " " + selector_l2_block_number: wordsHex(1_200),
+					test_game_3 + " " + selector_status:          wordsHex(tt.game_status),
+					test_game_3 + " " + selector_resolved_at:     wordsHex(1_700_400_000),
+				},
+			}
+			caller := newFakeCaller(t, root.handlers())
+			g := NewOptimismGames(caller, 12*time.Second, chain.DisputeGames{
+				Factory: test_factory,
+				AirGap:  chain.Duration{Duration: air_gap},
+			}, test_l2_genesis)
+
+			for i := 0; i < 2; i++ {
+				got, err := g.Settle(testRootL2Hash(1_000), chain.RootTx{RootBlock: 100})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want {
+					t.Fatalf("got %+v, want %+v", got, tt.want)
+				}
+			}
+			want_scans := 2
+			if tt.want_kept {
+				want_scans = 1
+			}
+			if caller.calls["eth_getLogs"] != want_scans {
+				t.Fatalf("got %d scans, want %d", caller.calls["eth_getLogs"], want_scans)
+			}
+		})
+	}
+}
+
+func TestOptimismGamesNoGame(t *testing.T) {
+	root := &fakeRoot{head: 1_000}
+	g := NewOptimismGames(newFakeCaller(t, root.handlers()), 12*time.Second, chain.DisputeGames{Factory: test_factory}, test_l2_genesis)
+
+	got, err := g.Settle(testRootL2Hash(1_000), chain.RootTx{RootBlock: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != (chain.Settlement{}) {
+		t.Fatalf("got %+v, want no settlement yet", got)
+	}
+}
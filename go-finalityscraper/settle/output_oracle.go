@@ -0,0 +1,101 @@
+package settle
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"strconv"
+	"time"
+)
+
+// OutputProposed(bytes32 indexed outputRoot, uint256 indexed l2OutputIndex, uint256 indexed l2BlockNumber, uint256 l1Timestamp)
+const topic_output_proposed = "0xa7aaf2512769da4e444e3de247be2564225c2e7a8f74cfe528e46e17d24868e2"
+
+// Finds the first output proposed for an L2 block at or after the tx, after its batch
+// was posted. Outputs are not resolved, withdrawals can be finalized once the
+// challenge period passed, so that is both its resolution & finalization.
+// The output depends on the L2 block of each tx, not its batch.
+type OutputOracle struct {
+	root             *rpc.Eth
+	scan             scan
+	oracle           chain.OutputOracle
+	genesis          chain.Genesis
+	challenge_period time.Duration
+	// L2 block -> settlement by its output
+	settled *settled
+}
+
+func NewOutputOracle(root rpc.Caller, root_block_time time.Duration, oracle chain.OutputOracle, genesis chain.Genesis, challenge_period time.Duration) *OutputOracle {
+	return &OutputOracle{
+		root:             rpc.NewEth(root),
+		scan:             newScan(root_block_time),
+		oracle:           oracle,
+		genesis:          genesis,
+		challenge_period: challenge_period,
+		settled:          newSettled(),
+	}
+}
+
+func (o *OutputOracle) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	l2_block := o.genesis.BlockAt(root_l2_hash.Start / 1000)
+	settlement, settlement_exists := o.settled.get(l2_block)
+	if settlement_exists {
+		return settlement, nil
+	}
+
+	from, from_err := rootBlock(ctx, o.root, root_tx)
+	if from_err != nil {
+		return chain.Settlement{}, from_err
+	}
+
+	// Zero while there is no output yet
+	settlement = chain.Settlement{}
+	_, scan_err := scanLogs(ctx, o.root, o.scan, rpc.LogFilter{
+		Address: o.oracle.Address,
+		Topics:  rpc.Topics(topic_output_proposed),
+	}, from, func(log rpc.Log) (bool, error) {
+		output_settlement, output_ok, output_err := o.outputSettlement(ctx, log, l2_block)
+		if output_ok {
+			settlement = output_settlement
+		}
+		return output_ok, output_err
+	})
+	if scan_err == nil {
+		o.settled.set(l2_block, settlement)
+	}
+	return settlement, scan_err
+}
+
+// Settlement by the output proposed in log, false if it is for an older L2 block
+func (o *OutputOracle) outputSettlement(ctx context.Context, log rpc.Log, l2_block uint64) (chain.Settlement, bool, error) {
+	if len(log.Topics) < 4 {
+		return chain.Settlement{}, false, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("OutputProposed without l2BlockNumber: %s", log.TxHash))
+	}
+	output_l2_block, output_l2_block_err := topicUint(log.Topics[3])
+	if output_l2_block_err != nil {
+		return chain.Settlement{}, false, output_l2_block_err
+	}
+	if output_l2_block < l2_block {
+		return chain.Settlement{}, false, nil
+	}
+	index, index_err := topicUint(log.Topics[2])
+	if index_err != nil {
+		return chain.Settlement{}, false, index_err
+	}
+
+	proposed, proposed_err := blockTime(ctx, o.root, uint64(log.BlockNumber))
+	if proposed_err != nil {
+		return chain.Settlement{}, false, proposed_err
+	}
+	finalized := proposed + o.challenge_period.Milliseconds()
+	return chain.Settlement{
+		Id:         strconv.FormatUint(index, 10),
+		Proposed:   proposed,
+		Resolved:   finalized,
+		Finalized:  finalized,
+		ProposedTx: log.TxHash,
+	}, true, nil
+}
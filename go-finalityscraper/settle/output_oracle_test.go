@@ -0,0 +1,100 @@
+package settle
+
+import (
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"testing"
+	"time"
+)
+
+const test_oracle = "0x00000000000000000000000000000000000000c0"
+
+func outputProposed(block uint64, index uint64, l2_block uint64) rpc.Log {
+	return rpc.Log{
+		Address:     test_oracle,
+		Topics:      []string{topic_output_proposed, abi.UintHex(0), abi.UintHex(index), abi.UintHex(l2_block)},
+		BlockNumber: rpc.Quantity(block),
+		TxHash:      fmt.Sprintf("0xpropose%02d", index),
+	}
+}
+
+func TestOutputOracleSettle(t *testing.T) {
+	challenge_period := 168 * time.Hour
+	root := &fakeRoot{
+		head: 1_000,
+		logs: []rpc.Log{
+			outputProposed(110, 6, 900),
+			outputProposed(130, 7, 1_200),
+			outputProposed(190, 8, 1_800),
+		},
+	}
+	caller := newFakeCaller(t, root.handlers())
+	o := NewOutputOracle(caller, 12*time.Second, chain.OutputOracle{Address: test_oracle}, test_l2_genesis, challenge_period)
+
+	// Txs of one batch are covered by the output of their own L2 block
+	tests := []struct {
+		l2_block int64
+		want     chain.Settlement
+	}{
+		{
+			l2_block: 1_000,
+			want: chain.Settlement{
+				Id:         "7",
+				Proposed:   testBlockTime(130),
+				Resolved:   testBlockTime(130) + challenge_period.Milliseconds(),
+				Finalized:  testBlockTime(130) + challenge_period.Milliseconds(),
+				ProposedTx: "0xpropose07",
+			},
+		},
+		{
+			l2_block: 1_250,
+			want: chain.Settlement{
+				Id:         "8",
+				Proposed:   testBlockTime(190),
+				Resolved:   testBlockTime(190) + challenge_period.Milliseconds(),
+				Finalized:  testBlockTime(190) + challenge_period.Milliseconds(),
+				ProposedTx: "0xpropose08",
+			},
+		},
+		// Kept by L2 block
+		{
+			l2_block: 1_000,
+			want: chain.Settlement{
+				Id:         "7",
+				Proposed:   testBlockTime(130),
+				Resolved:   testBlockTime(130) + challenge_period.Milliseconds(),
+				Finalized:  testBlockTime(130) + challenge_period.Milliseconds(),
+				ProposedTx: "0xpropose07",
+			},
+		},
+		// No output yet
+		{l2_block: 2_000},
+	}
+	for _, tt := range tests {
+		got, err := o.Settle(testRootL2Hash(tt.l2_block), chain.RootTx{RootBlock: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("L2 block %d: got %+v, want %+v", tt.l2_block, got, tt.want)
+		}
+	}
+	if caller.calls["eth_getLogs"] != 3 {
+		t.Fatalf("got %d scans, want 3", caller.calls["eth_getLogs"])
+	}
+}
+
+func TestOutputOracleSettleInvalidLog(t *testing.T) {
+	root := &fakeRoot{
+		head: 1_000,
+		logs: []rpc.Log{{Address: test_oracle, Topics: []string{topic_output_proposed, abi.UintHex(0)}, BlockNumber: 110}},
+	}
+	o := NewOutputOracle(newFakeCaller(t, root.handlers()), 12*time.Second, chain.OutputOracle{Address: test_oracle}, test_l2_genesis, time.Hour)
+
+	_, err := o.Settle(testRootL2Hash(1_000), chain.RootTx{RootBlock: 100})
+	if err == nil {
+		t.Fatal("got no error for an output without l2BlockNumber")
+	}
+}
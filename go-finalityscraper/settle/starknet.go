@@ -0,0 +1,100 @@
+package settle
+
+import (
+	"context"
+	"fmt"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"strconv"
+	"time"
+)
+
+// LogStateUpdate(uint256 globalRoot, int256 blockNumber, uint256 blockHash)
+const topic_log_state_update = "0xd342ddf7a308dec111745b00315c14b7efb2bdae570a6856e088ed0c65a3576c"
+
+const state_update_block_word = 1
+
+// stateBlockNumber()
+const selector_state_block_number = "0x35befa5d"
+
+// Starknet core contract on the root chain. A state update moves the L1 state to an
+// L2 block once the proof of every block up to it is verified, so the first one at
+// or after a block is when it is ACCEPTED_ON_L1: posted, proven & final at once.
+type StarknetCore struct {
+	root *rpc.Eth
+	scan scan
+	core chain.StarknetCore
+}
+
+func NewStarknetCore(root rpc.Caller, root_block_time time.Duration, core chain.StarknetCore) *StarknetCore {
+	return &StarknetCore{
+		root: rpc.NewEth(root),
+		scan: newScan(root_block_time),
+		core: core,
+	}
+}
+
+// L2 block of the current L1 state, every block up to it is ACCEPTED_ON_L1
+func (s *StarknetCore) StateBlock(ctx context.Context) (uint64, error) {
+	return callUint(ctx, s.root, s.core.Address, selector_state_block_number)
+}
+
+// First state update tx covering l2_block from L1 block from on, empty if there is none yet
+func (s *StarknetCore) StateUpdate(ctx context.Context, l2_block uint64, from uint64) (string, error) {
+	tx_hash := ""
+	_, scan_err := scanLogs(ctx, s.root, s.scan, rpc.LogFilter{
+		Address: s.core.Address,
+		Topics:  rpc.Topics(topic_log_state_update),
+	}, from, func(log rpc.Log) (bool, error) {
+		update_block, update_block_err := stateUpdateBlock(log)
+		if update_block_err != nil || update_block < l2_block {
+			return false, update_block_err
+		}
+		tx_hash = log.TxHash
+		return true, nil
+	})
+	return tx_hash, scan_err
+}
+
+// The batch tx of a Starknet tx is its state update, see StateUpdate
+func (s *StarknetCore) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	tx_hash, tx_hash_err := hrefHash(root_l2_hash.Href)
+	if tx_hash_err != nil {
+		return chain.Settlement{}, tx_hash_err
+	}
+	receipt, receipt_err := s.root.GetTransactionReceipt(ctx, tx_hash)
+	if receipt_err != nil || receipt == nil {
+		return chain.Settlement{}, receipt_err
+	}
+	for _, log := range receipt.Logs {
+		if len(log.Topics) == 0 || log.Topics[0] != topic_log_state_update {
+			continue
+		}
+		update_block, update_block_err := stateUpdateBlock(log)
+		if update_block_err != nil {
+			return chain.Settlement{}, update_block_err
+		}
+		return chain.Settlement{
+			Id:          strconv.FormatUint(update_block, 10),
+			Proposed:    root_tx.RootEnd,
+			Resolved:    root_tx.RootEnd,
+			Finalized:   root_tx.RootEnd,
+			ProposedTx:  tx_hash,
+			ResolvedTx:  tx_hash,
+			FinalizedTx: tx_hash,
+		}, nil
+	}
+	return chain.Settlement{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("No LogStateUpdate in %s", tx_hash))
+}
+
+// L2 block the state update moves to
+func stateUpdateBlock(log rpc.Log) (uint64, error) {
+	data, data_err := abi.DecodeHex(log.Data)
+	if data_err != nil {
+		return 0, data_err
+	}
+	return abi.Uint(data, state_update_block_word)
+}
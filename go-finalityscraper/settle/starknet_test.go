@@ -0,0 +1,119 @@
+package settle
+
+import (
+	"context"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"testing"
+	"time"
+)
+
+const test_core = "0x00000000000000000000000000000000000000d0"
+
+// LogStateUpdate(globalRoot, blockNumber, blockHash), blockNumber is word 1
+func stateUpdate(block uint64, l2_block uint64, tx_hash string) rpc.Log {
+	return rpc.Log{
+		Address:     test_core,
+		Topics:      []string{topic_log_state_update},
+		Data:        wordsHex(0xaaaa, l2_block, 0xbbbb),
+		BlockNumber: rpc.Quantity(block),
+		TxHash:      tx_hash,
+	}
+}
+
+func TestStarknetCoreStateUpdate(t *testing.T) {
+	root := &fakeRoot{
+		head: 1_000,
+		logs: []rpc.Log{
+			stateUpdate(90, 640_000, "0xearlier"),
+			stateUpdate(110, 640_010, "0xupdate1"),
+			stateUpdate(140, 640_020, "0xupdate2"),
+		},
+		calls: map[string]string{
+			test_core + " " + selector_state_block_number: wordsHex(640_020),
+		},
+	}
+	s := NewStarknetCore(newFakeCaller(t, root.handlers()), 12*time.Second, chain.StarknetCore{Address: test_core})
+	ctx := context.Background()
+
+	state_block, err := s.StateBlock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state_block != 640_020 {
+		t.Fatalf("got state block %d, want 640020", state_block)
+	}
+
+	tests := []struct {
+		l2_block uint64
+		want     string
+	}{
+		{l2_block: 640_005, want: "0xupdate1"},
+		{l2_block: 640_010, want: "0xupdate1"},
+		{l2_block: 640_011, want: "0xupdate2"},
+		// Not ACCEPTED_ON_L1 yet
+		{l2_block: 640_021, want: ""},
+	}
+	for _, tt := range tests {
+		got, err := s.StateUpdate(ctx, tt.l2_block, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("L2 block %d: got %q, want %q", tt.l2_block, got, tt.want)
+		}
+	}
+}
+
+func TestStarknetCoreSettle(t *testing.T) {
+	receipts := map[string]any{
+		"0xupdate": map[string]any{
+			"blockNumber": rpc.Quantity(110),
+			"status":      rpc.Quantity(1),
+			"logs": []rpc.Log{
+				{Address: test_core, Topics: []string{"0xother"}, Data: wordsHex(1)},
+				stateUpdate(110, 640_010, "0xupdate"),
+			},
+		},
+		"0xnoupdate": map[string]any{"blockNumber": rpc.Quantity(110), "status": rpc.Quantity(1), "logs": []rpc.Log{}},
+		"0xpending":  nil,
+	}
+	s := NewStarknetCore(newFakeCaller(t, fakeHandlers{
+		"eth_getTransactionReceipt": func(params []any) any {
+			return receipts[params[0].(string)]
+		},
+	}), 12*time.Second, chain.StarknetCore{Address: test_core})
+
+	tests := []struct {
+		tx_hash  string
+		want     chain.Settlement
+		want_err bool
+	}{
+		{
+			// Posted, proven & final at once
+			tx_hash: "0xupdate",
+			want: chain.Settlement{
+				Id:          "640010",
+				Proposed:    testBlockTime(110),
+				Resolved:    testBlockTime(110),
+				Finalized:   testBlockTime(110),
+				ProposedTx:  "0xupdate",
+				ResolvedTx:  "0xupdate",
+				FinalizedTx: "0xupdate",
+			},
+		},
+		{tx_hash: "0xpending"},
+		{tx_hash: "0xnoupdate", want_err: true},
+	}
+	for _, tt := range tests {
+		root_l2_hash := testRootL2Hash(0)
+		root_l2_hash.Href = "https://etherscan.io/tx/" + tt.tx_hash
+		got, err := s.Settle(root_l2_hash, chain.RootTx{RootEnd: testBlockTime(110)})
+		if (err != nil) != tt.want_err {
+			t.Fatalf("%s: got error %v, want error %t", tt.tx_hash, err, tt.want_err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.tx_hash, got, tt.want)
+		}
+	}
+}
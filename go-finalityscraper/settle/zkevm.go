@@ -0,0 +1,73 @@
+package settle
+
+import (
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"strconv"
+)
+
+// Reads the batch of the tx from a Polygon zkEVM node. It is trusted once the
+// trusted sequencer closed it, virtual once sequenced on L1, then consolidated
+// once its proof is verified on L1, when withdrawals can be claimed. Sequencing
+// is the proposed step, consolidation both resolved & finalized. The batch is read
+// per tx, one sequencing tx on L1 covers several batches.
+type ZkEvmBatches struct {
+	l2    *rpc.Eth
+	zkevm *rpc.ZkEvm
+	root  *rpc.Eth
+	// Batch number -> consolidated settlement
+	settled *settled
+}
+
+func NewZkEvmBatches(l2 rpc.Caller, root rpc.Caller) *ZkEvmBatches {
+	return &ZkEvmBatches{
+		l2:      rpc.NewEth(l2),
+		zkevm:   rpc.NewZkEvm(l2),
+		root:    rpc.NewEth(root),
+		settled: newSettled(),
+	}
+}
+
+func (z *ZkEvmBatches) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	tx, tx_err := z.l2.GetTransactionByHash(ctx, root_l2_hash.Hash)
+	if tx_err != nil || tx == nil || tx.BlockNumber == nil {
+		return chain.Settlement{}, tx_err
+	}
+	batch_number, batch_number_err := z.zkevm.BatchNumberByBlockNumber(ctx, uint64(*tx.BlockNumber))
+	if batch_number_err != nil {
+		return chain.Settlement{}, batch_number_err
+	}
+	settlement, settlement_exists := z.settled.get(batch_number)
+	if settlement_exists {
+		return settlement, nil
+	}
+	batch, batch_err := z.zkevm.GetBatchByNumber(ctx, batch_number)
+	if batch_err != nil || batch == nil {
+		return chain.Settlement{}, batch_err
+	}
+
+	settlement = chain.Settlement{
+		Id:      strconv.FormatUint(batch_number, 10),
+		Trusted: batch.TimeMs(),
+	}
+	sequence_tx := batch.SequenceTx()
+	if sequence_tx != "" {
+		proposed, proposed_err := txTime(ctx, z.root, sequence_tx)
+		if proposed_err != nil {
+			return chain.Settlement{}, proposed_err
+		}
+		settlement.Proposed, settlement.ProposedTx = proposed, sequence_tx
+	}
+	verify_tx := batch.VerifyTx()
+	if verify_tx != "" {
+		finalized, finalized_err := txTime(ctx, z.root, verify_tx)
+		if finalized_err != nil {
+			return chain.Settlement{}, finalized_err
+		}
+		settlement.Resolved, settlement.ResolvedTx = finalized, verify_tx
+		settlement.Finalized, settlement.FinalizedTx = finalized, verify_tx
+	}
+	z.settled.set(batch_number, settlement)
+	return settlement, nil
+}
@@ -0,0 +1,80 @@
+package settle
+
+import (
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"testing"
+)
+
+func TestZkEvmBatchesSettle(t *testing.T) {
+	zero_hash := "0x0000000000000000000000000000000000000000000000000000000000000000"
+	// L2 block by tx, batch by L2 block
+	l2_blocks := map[string]any{
+		"0xconsolidated": rpc.Quantity(10),
+		"0xsame":         rpc.Quantity(11),
+		"0xvirtual":      rpc.Quantity(12),
+		"0xtrusted":      rpc.Quantity(13),
+		"0xpending":      nil,
+	}
+	batch_numbers := map[rpc.Quantity]rpc.Quantity{10: 70, 11: 70, 12: 71, 13: 72}
+	batches := map[rpc.Quantity]map[string]any{
+		70: {"number": rpc.Quantity(70), "timestamp": rpc.Quantity(1_700_000_010), "sendSequencesTxHash": "0xsequence", "verifyBatchTxHash": "0xverify"},
+		71: {"number": rpc.Quantity(71), "timestamp": rpc.Quantity(1_700_000_020), "sendSequencesTxHash": "0xsequence", "verifyBatchTxHash": zero_hash},
+		72: {"number": rpc.Quantity(72), "timestamp": rpc.Quantity(1_700_000_030), "sendSequencesTxHash": nil, "verifyBatchTxHash": nil},
+	}
+	l2 := newFakeCaller(t, fakeHandlers{
+		"eth_getTransactionByHash": func(params []any) any {
+			return map[string]any{"hash": params[0], "blockNumber": l2_blocks[params[0].(string)]}
+		},
+		"zkevm_batchNumberByBlockNumber": func(params []any) any {
+			return batch_numbers[params[0].(rpc.Quantity)]
+		},
+		"zkevm_getBatchByNumber": func(params []any) any {
+			return batches[params[0].(rpc.Quantity)]
+		},
+	})
+	root := &fakeRoot{head: 1_000}
+	root_handlers := root.handlers()
+	root_handlers["eth_getTransactionReceipt"] = func(params []any) any {
+		receipt_blocks := map[string]rpc.Quantity{"0xsequence": 120, "0xverify": 200}
+		return map[string]any{"blockNumber": receipt_blocks[params[0].(string)], "status": rpc.Quantity(1)}
+	}
+	z := NewZkEvmBatches(l2, newFakeCaller(t, root_handlers))
+
+	consolidated := chain.Settlement{
+		Id:          "70",
+		Trusted:     1_700_000_010_000,
+		Proposed:    testBlockTime(120),
+		Resolved:    testBlockTime(200),
+		Finalized:   testBlockTime(200),
+		ProposedTx:  "0xsequence",
+		ResolvedTx:  "0xverify",
+		FinalizedTx: "0xverify",
+	}
+	tests := []struct {
+		hash string
+		want chain.Settlement
+	}{
+		{hash: "0xconsolidated", want: consolidated},
+		// Kept by batch number
+		{hash: "0xsame", want: consolidated},
+		// Zero hashes are L1 txs that did not happen yet
+		{hash: "0xvirtual", want: chain.Settlement{Id: "71", Trusted: 1_700_000_020_000, Proposed: testBlockTime(120), ProposedTx: "0xsequence"}},
+		{hash: "0xtrusted", want: chain.Settlement{Id: "72", Trusted: 1_700_000_030_000}},
+		{hash: "0xpending"},
+	}
+	for _, tt := range tests {
+		root_l2_hash := testRootL2Hash(0)
+		root_l2_hash.Hash = tt.hash
+		got, err := z.Settle(root_l2_hash, chain.RootTx{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.hash, got, tt.want)
+		}
+	}
+	if l2.calls["zkevm_getBatchByNumber"] != 3 {
+		t.Fatalf("got %d batches read, want 3", l2.calls["zkevm_getBatchByNumber"])
+	}
+}
@@ -0,0 +1,60 @@
+package settle
+
+import (
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"strconv"
+	"time"
+)
+
+// Reads the L1 batch of the tx from a zkSync Era node. Its state is committed,
+// proven (the validity proof verified on L1), then executed, when withdrawals
+// are final. Those are the proposed, resolved & finalized steps. The batch is read
+// per tx, a batch tx on L1 commits several L1 batches.
+type ZkSyncBatches struct {
+	l2  *rpc.Eth
+	zks *rpc.Zks
+	// L1 batch number -> executed settlement
+	settled *settled
+}
+
+func NewZkSyncBatches(l2 rpc.Caller) *ZkSyncBatches {
+	return &ZkSyncBatches{
+		l2:      rpc.NewEth(l2),
+		zks:     rpc.NewZks(l2),
+		settled: newSettled(),
+	}
+}
+
+func (z *ZkSyncBatches) Settle(root_l2_hash chain.RootL2Hash, root_tx chain.RootTx) (chain.Settlement, error) {
+	ctx := root_l2_hash.Ctx
+	tx, tx_err := z.l2.GetTransactionByHash(ctx, root_l2_hash.Hash)
+	if tx_err != nil || tx == nil || tx.L1BatchNumber == nil {
+		return chain.Settlement{}, tx_err
+	}
+	settlement, settlement_exists := z.settled.get(uint64(*tx.L1BatchNumber))
+	if settlement_exists {
+		return settlement, nil
+	}
+	batch, batch_err := z.zks.GetL1BatchDetails(ctx, uint64(*tx.L1BatchNumber))
+	if batch_err != nil || batch == nil {
+		return chain.Settlement{}, batch_err
+	}
+
+	settlement = chain.Settlement{
+		Id: strconv.FormatUint(batch.Number, 10),
+	}
+	settlement.Proposed, settlement.ProposedTx = zksStep(batch.CommittedAt, batch.CommitTxHash)
+	settlement.Resolved, settlement.ResolvedTx = zksStep(batch.ProvenAt, batch.ProveTxHash)
+	settlement.Finalized, settlement.FinalizedTx = zksStep(batch.ExecutedAt, batch.ExecuteTxHash)
+	z.settled.set(batch.Number, settlement)
+	return settlement, nil
+}
+
+// unix ms & L1 tx of a batch step, zero until it happened
+func zksStep(at *time.Time, tx_hash *string) (int64, string) {
+	if at == nil || tx_hash == nil {
+		return 0, ""
+	}
+	return at.UnixMilli(), *tx_hash
+}
@@ -0,0 +1,94 @@
+package settle
+
+import (
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"testing"
+	"time"
+)
+
+func TestZkSyncBatchesSettle(t *testing.T) {
+	committed := time.Unix(1_700_000_100, 0).UTC()
+	proven := time.Unix(1_700_003_600, 0).UTC()
+	executed := time.Unix(1_700_010_800, 0).UTC()
+	// L1 batch by tx
+	l1_batches := map[string]any{
+		"0xexecuted":  rpc.Quantity(500),
+		"0xsame":      rpc.Quantity(500),
+		"0xcommitted": rpc.Quantity(501),
+		"0xsealing":   nil,
+	}
+	caller := newFakeCaller(t, fakeHandlers{
+		"eth_getTransactionByHash": func(params []any) any {
+			return map[string]any{"hash": params[0], "l1BatchNumber": l1_batches[params[0].(string)]}
+		},
+		"zks_getL1BatchDetails": func(params []any) any {
+			switch params[0].(uint64) {
+			case 500:
+				return map[string]any{
+					"number": 500, "timestamp": 1_700_000_000, "status": "verified",
+					"commitTxHash": "0xcommit", "committedAt": committed,
+					"proveTxHash": "0xprove", "provenAt": proven,
+					"executeTxHash": "0xexecute", "executedAt": executed,
+				}
+			default:
+				return map[string]any{
+					"number": 501, "timestamp": 1_700_000_000, "status": "sealed",
+					"commitTxHash": "0xcommit", "committedAt": committed,
+				}
+			}
+		},
+	})
+	z := NewZkSyncBatches(caller)
+
+	tests := []struct {
+		hash string
+		want chain.Settlement
+	}{
+		{
+			hash: "0xexecuted",
+			want: chain.Settlement{
+				Id:          "500",
+				Proposed:    committed.UnixMilli(),
+				Resolved:    proven.UnixMilli(),
+				Finalized:   executed.UnixMilli(),
+				ProposedTx:  "0xcommit",
+				ResolvedTx:  "0xprove",
+				FinalizedTx: "0xexecute",
+			},
+		},
+		{
+			// Kept by L1 batch number
+			hash: "0xsame",
+			want: chain.Settlement{
+				Id:          "500",
+				Proposed:    committed.UnixMilli(),
+				Resolved:    proven.UnixMilli(),
+				Finalized:   executed.UnixMilli(),
+				ProposedTx:  "0xcommit",
+				ResolvedTx:  "0xprove",
+				FinalizedTx: "0xexecute",
+			},
+		},
+		{
+			hash: "0xcommitted",
+			want: chain.Settlement{Id: "501", Proposed: committed.UnixMilli(), ProposedTx: "0xcommit"},
+		},
+		// Not in an L1 batch yet
+		{hash: "0xsealing"},
+	}
+	for _, tt := range tests {
+		root_l2_hash := testRootL2Hash(0)
+		root_l2_hash.Hash = tt.hash
+		got, err := z.Settle(root_l2_hash, chain.RootTx{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Fatalf("%s: got %+v, want %+v", tt.hash, got, tt.want)
+		}
+	}
+	if caller.calls["zks_getL1BatchDetails"] != 2 {
+		t.Fatalf("got %d batches read, want 2", caller.calls["zks_getL1BatchDetails"])
+	}
+}
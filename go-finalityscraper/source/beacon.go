@@ -0,0 +1,47 @@
+package source
+
+import (
+	"go-finalityscraper/beacon"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"os"
+	"sync"
+)
+
+// .env BEACON_URL_<chain_id>, empty if the root chain has no beacon node
+func EnvBeaconUrl(chain_id chain.ChainId) string {
+	return os.Getenv("BEACON_URL_" + string(chain_id))
+}
+
+// Beacon finality per root chain, shared by every root_b so epochs are only looked up once
+type Finalities struct {
+	mu *sync.Mutex
+	m  map[chain.ChainId]*beacon.Finality
+}
+
+func NewFinalities() *Finalities {
+	return &Finalities{
+		mu: &sync.Mutex{},
+		m:  map[chain.ChainId]*beacon.Finality{},
+	}
+}
+
+// chain_id of the L2, nil if its root chain has no beacon node
+func (finalities *Finalities) Get(chain_id chain.ChainId) (*beacon.Finality, error) {
+	root_chain_id, root_chain_id_err := chain.MapChainIdRoot(chain_id)
+	if root_chain_id_err != nil {
+		return nil, status.NewErr(status.ErrCodeUnknownChain, root_chain_id_err)
+	}
+	finalities.mu.Lock()
+	defer finalities.mu.Unlock()
+	finality, finality_exists := finalities.m[root_chain_id]
+	if finality_exists {
+		return finality, nil
+	}
+	url := EnvBeaconUrl(root_chain_id)
+	if url != "" {
+		finality = beacon.NewFinality(beacon.NewClient(url))
+	}
+	finalities.m[root_chain_id] = finality
+	return finality, nil
+}
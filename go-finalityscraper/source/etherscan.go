@@ -0,0 +1,131 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/etherscan"
+	"go-finalityscraper/rpc"
+	"os"
+	"strings"
+	"time"
+)
+
+// Like RpcL2, through the explorer APIs: module=proxy for the L2 tx,
+// module=account txlist of the batch inbox for the batch
+func NewEtherscanL2(chain_id chain.ChainId) (*RpcL2, error) {
+	root_chain_id, root_chain_id_err := chain.MapChainIdRoot(chain_id)
+	if root_chain_id_err != nil {
+		return nil, root_chain_id_err
+	}
+	root_url, root_url_err := chain.MapChainIdRootUrl(chain_id)
+	if root_url_err != nil {
+		return nil, root_url_err
+	}
+	inbox, inbox_err := chain.MapChainIdBatchInbox(chain_id)
+	if inbox_err != nil {
+		return nil, inbox_err
+	}
+	l2, l2_err := envEtherscan(chain_id)
+	if l2_err != nil {
+		return nil, l2_err
+	}
+	root, root_err := envEtherscan(root_chain_id)
+	if root_err != nil {
+		return nil, root_err
+	}
+
+	root_block_time, root_block_time_err := chain.MapChainIdAvgBlockTime(root_chain_id)
+	if root_block_time_err != nil {
+		return nil, root_block_time_err
+	}
+
+	return NewEtherscanL2With(l2, root, root_block_time, root_url, inbox, mapOpStack(chain_id)), nil
+}
+
+// op_stack nil for other stacks
+func NewEtherscanL2With(l2 *etherscan.Client, root *etherscan.Client, root_block_time time.Duration, root_url chain.ChainUrl, inbox string, op_stack *chain.OpStack) *RpcL2 {
+	return &RpcL2{
+		l2: rpc.NewEth(l2),
+		batches: &etherscanBatches{
+			root:        root,
+			scan_blocks: chain.BlocksIn(batch_scan_time, root_block_time),
+			inbox:       inbox,
+			batcher:     newOpBatcher(root, op_stack),
+		},
+		root_url: root_url,
+	}
+}
+
+// Like RpcRoot, through module=proxy
+func NewEtherscanRoot(root_chain_id chain.ChainId) (*RpcRoot, error) {
+	root, root_err := envEtherscan(root_chain_id)
+	if root_err != nil {
+		return nil, root_err
+	}
+	return NewRpcRootWith(root), nil
+}
+
+// .env ETHERSCAN_API_KEY_<chain_id>, ETHERSCAN_API_URL_<chain_id> overrides the default API
+func envEtherscan(chain_id chain.ChainId) (*etherscan.Client, error) {
+	api_url := os.Getenv("ETHERSCAN_API_URL_" + string(chain_id))
+	if api_url == "" {
+		default_url, default_url_err := chain.MapChainIdApiUrl(chain_id)
+		if default_url_err != nil {
+			return nil, default_url_err
+		}
+		api_url = default_url
+	}
+	api_key := os.Getenv("ETHERSCAN_API_KEY_" + string(chain_id))
+	if api_key == "" {
+		return nil, fmt.Errorf("ETHERSCAN_API_KEY_%s not set", chain_id)
+	}
+	return etherscan.NewClient(api_url, api_key), nil
+}
+
+// Lists txs to the inbox instead of scanning blocks
+type etherscanBatches struct {
+	root *etherscan.Client
+	// Root chain blocks in batch_scan_time
+	scan_blocks uint64
+	inbox       string
+	// nil if any sender is taken
+	batcher *opBatcher
+}
+
+// Inbox txs listed per lookup, the first successful one is the batch
+const batch_list_limit = 100
+
+func (s *etherscanBatches) findBatch(l2_hash chain.L2Hash, l2_block uint64, start int64) (string, error) {
+	ctx := l2_hash.Ctx
+	from, from_err := s.root.BlockBeforeTime(ctx, start)
+	if from_err != nil {
+		return "", from_err
+	}
+	head, head_err := rpc.NewEth(s.root).BlockNumber(ctx)
+	if head_err != nil {
+		return "", head_err
+	}
+
+	to := from + s.scan_blocks
+	txs, txs_err := s.root.TxList(ctx, s.inbox, from, to, batch_list_limit)
+	if txs_err != nil {
+		return "", txs_err
+	}
+	for _, tx := range txs {
+		if !strings.EqualFold(tx.To, s.inbox) || tx.IsError != "0" {
+			continue
+		}
+		allowed, allowed_err := s.batcher.allows(ctx, tx.From)
+		if allowed_err != nil {
+			return "", allowed_err
+		}
+		if allowed && mayContain(l2_hash.ChainId, 0, tx.Input, l2_block) {
+			return tx.Hash, nil
+		}
+	}
+	if to > head {
+		return "", nil
+	}
+	return "", status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("No batch within %d blocks of %s", s.scan_blocks, l2_hash.Hash))
+}
@@ -0,0 +1,110 @@
+package source
+
+import (
+	"encoding/json"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/etherscan"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// module.action -> body
+type apiHandlers map[string]func(query map[string][]string) string
+
+// Local stand-in for an explorer API, unknown actions fail the test
+func newApiServer(t *testing.T, handlers apiHandlers) *etherscan.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		action := query.Get("module") + "." + query.Get("action")
+		handler, handler_exists := handlers[action]
+		if !handler_exists {
+			t.Errorf("Unexpected call: %s", action)
+			return
+		}
+		w.Write([]byte(handler(query)))
+	}))
+	t.Cleanup(server.Close)
+	return etherscan.NewClient(server.URL, "key")
+}
+
+func proxyResult(result any) string {
+	body, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "result": result})
+	return string(body)
+}
+
+// L2 API with tx 0xa at block 16, at 1000s
+func newL2Api(t *testing.T) *etherscan.Client {
+	return newApiServer(t, apiHandlers{
+		"proxy.eth_getTransactionByHash": func(query map[string][]string) string {
+			return proxyResult(map[string]any{"hash": "0xa", "blockNumber": quantity(16)})
+		},
+		"proxy.eth_getBlockByNumber": func(query map[string][]string) string {
+			return proxyResult(map[string]any{"number": query["tag"][0], "timestamp": quantity(1000)})
+		},
+	})
+}
+
+func TestEtherscanL2Tx(t *testing.T) {
+	batch_list := `{"status":"1","message":"OK","result":[` +
+		`{"hash":"0xfailed","from":"0x1","to":"` + test_inbox + `","input":"0x00","isError":"1"},` +
+		`{"hash":"0xbatch","from":"0x1","to":"` + test_inbox + `","input":"0x00","isError":"0"}]}`
+	no_txs := `{"status":"0","message":"No transactions found","result":[]}`
+	tests := []struct {
+		name string
+		// Of the root chain
+		block_time time.Duration
+		txlist     string
+		head       uint64
+		want       L2Tx
+		want_code  status.ErrCode
+	}{
+		{name: "batch found", block_time: test_block_time, txlist: batch_list, head: 1000, want: L2Tx{Start: 1_000_000, Href: "https://etherscan.io/tx/0xbatch"}},
+		{name: "not batched yet", block_time: test_block_time, txlist: no_txs, head: 10, want: L2Tx{Start: 1_000_000}},
+		{name: "no batch in the window", block_time: test_block_time, txlist: no_txs, head: 1000, want_code: status.ErrCodeScrapeFailed},
+		// An hour is 14,400 blocks of 250ms
+		{name: "window of a fast root chain", block_time: 250 * time.Millisecond, txlist: no_txs, head: 10_000, want: L2Tx{Start: 1_000_000}},
+		{name: "rate limit", block_time: test_block_time, txlist: `{"status":"0","message":"NOTOK","result":"Max rate limit reached"}`, head: 1000, want_code: status.ErrCodeExplorerUnavailable},
+		{name: "malformed body", block_time: test_block_time, txlist: `<html>Just a moment...</html>`, head: 1000, want_code: status.ErrCodeScrapeFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newApiServer(t, apiHandlers{
+				"block.getblocknobytime": func(query map[string][]string) string {
+					return `{"status":"1","message":"OK","result":"1"}`
+				},
+				"proxy.eth_blockNumber": func(query map[string][]string) string {
+					return proxyResult(quantity(tt.head))
+				},
+				"account.txlist": func(query map[string][]string) string {
+					if query["address"][0] != test_inbox {
+						t.Errorf("Unexpected address: %s", query["address"][0])
+					}
+					// From the block before start, for an hour
+					want_end := 1 + uint64(time.Hour/tt.block_time)
+					if query["endblock"][0] != strconv.FormatUint(want_end, 10) {
+						t.Errorf("got endblock %s, want %d", query["endblock"][0], want_end)
+					}
+					return tt.txlist
+				},
+			})
+			s := NewEtherscanL2With(newL2Api(t), root, tt.block_time, "https://etherscan.io", test_inbox, nil)
+
+			got, err := s.L2Tx(testL2Hash("0xa"))
+			if tt.want_code != "" {
+				if status.CodeOf(err) != tt.want_code {
+					t.Fatalf("got error %v, want %s", err, tt.want_code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+)
+
+// Most hops from an L2 down to its root chain
+const max_hops = 8
+
+// Follows the batch tx of an L3 (or deeper) down to the root chain. The batch tx on
+// each parent is an L2 tx of that parent, read by its own L2 & root sources.
+// Each B has its own.
+type Hops struct {
+	l2_sources   L2Sources
+	root_sources RootSources
+}
+
+func NewHops() *Hops {
+	return &Hops{
+		l2_sources:   L2Sources{},
+		root_sources: RootSources{},
+	}
+}
+
+// Hops of the batch tx root_l2_hash.Href on the parent, see chain.RootTx.Hops. nil if
+// the parent is the root chain. A pending hop ends the list, it is the last with End 0.
+func (h *Hops) Resolve(root_l2_hash chain.RootL2Hash) ([]chain.Hop, error) {
+	parents, parents_err := chain.MapChainIdParents(root_l2_hash.ChainId)
+	if parents_err != nil {
+		return nil, parents_err
+	}
+	if len(parents) < 2 {
+		return nil, nil
+	}
+	if len(parents) > max_hops {
+		return nil, status.NewErr(status.ErrCodeUnknownChain, fmt.Errorf("Chain %s: more than %d hops", root_l2_hash.ChainId, max_hops))
+	}
+
+	hops := []chain.Hop{}
+	href := root_l2_hash.Href
+	for i := 1; i < len(parents); i++ {
+		hop, hop_err := h.hop(root_l2_hash, href, parents[i-1], parents[i])
+		if hop_err != nil {
+			return nil, hop_err
+		}
+		hops = append(hops, hop)
+		if hop.End == 0 {
+			break
+		}
+		href = hop.Href
+	}
+	return hops, nil
+}
+
+// Hop of the batch tx at href, an L2 tx of from_chain_id batched into to_chain_id
+func (h *Hops) hop(root_l2_hash chain.RootL2Hash, href string, from_chain_id chain.ChainId, to_chain_id chain.ChainId) (chain.Hop, error) {
+	hash, hash_err := HrefHash(href)
+	if hash_err != nil {
+		return chain.Hop{}, hash_err
+	}
+	hop := chain.Hop{
+		FromChain: from_chain_id,
+		ToChain:   to_chain_id,
+		Hash:      hash,
+	}
+
+	c, c_err := chain.Get(from_chain_id)
+	if c_err != nil {
+		return chain.Hop{}, c_err
+	}
+	l2_src, l2_src_err := h.l2_sources.Get(from_chain_id)
+	if l2_src_err != nil {
+		return chain.Hop{}, l2_src_err
+	}
+	l2_hash := chain.L2Hash{
+		Ctx:      root_l2_hash.Ctx,
+		ChainId:  from_chain_id,
+		ChainUrl: c.Url,
+		Hash:     hash,
+	}
+	l2_tx, l2_tx_err := l2_src.L2Tx(l2_hash)
+	if l2_tx_err != nil {
+		return chain.Hop{}, l2_tx_err
+	}
+	hop.Start = l2_tx.Start
+	if l2_tx.Href == "" {
+		return hop, nil
+	}
+	hop.Href = l2_tx.Href
+
+	root_src, root_src_err := h.root_sources.Get(from_chain_id)
+	if root_src_err != nil {
+		return chain.Hop{}, root_src_err
+	}
+	hop_root_tx, hop_root_tx_err := root_src.RootTx(chain.RootL2Hash{
+		L2Hash: l2_hash,
+		Href:   l2_tx.Href,
+		Start:  l2_tx.Start,
+	})
+	if hop_root_tx_err != nil {
+		return chain.Hop{}, hop_root_tx_err
+	}
+	hop.End = hop_root_tx.RootEnd
+	hop.LatencyMs = hop.End - hop.Start
+	return hop, nil
+}
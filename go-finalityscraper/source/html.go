@@ -0,0 +1,154 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/browser"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/parse"
+	"path"
+	"strconv"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Scrapes the L2 explorer tx page, with the selectors of the registry
+type HtmlL2 struct {
+	*browser.Browser
+}
+
+func NewHtmlL2() *HtmlL2 {
+	return &HtmlL2{browser.NewBrowser()}
+}
+
+func (s *HtmlL2) L2Tx(l2_hash chain.L2Hash) (L2Tx, error) {
+	c, c_err := chain.Get(l2_hash.ChainId)
+	if c_err != nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeUnknownChain, c_err)
+	}
+	if c.Selectors.Timestamp == "" || c.Selectors.BatchTx == "" {
+		return L2Tx{}, status.NewErr(status.ErrCodeUnknown, fmt.Errorf("No explorer selectors on chain %s, use SOURCE_%s=rpc", c.Id, c.Id))
+	}
+
+	url := string(c.Url) + c.Routes.Tx + l2_hash.Hash
+	open_err := s.Open(l2_hash.Ctx, url)
+	if open_err != nil {
+		return L2Tx{}, open_err
+	}
+
+	if s.queryNotFound() {
+		return L2Tx{}, status.NewErr(status.ErrCodeTxNotFound, fmt.Errorf("tx not found: %s", l2_hash.Hash))
+	}
+	// A timestamp means the tx exists, so a missing batch means it is not batched yet
+	start, start_err := parseTs(s.First(c.Selectors.Timestamp))
+	if start_err != nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, start_err)
+	}
+	l1StateBatchTx_el := s.First(c.Selectors.BatchTx)
+	if l1StateBatchTx_el == nil {
+		return L2Tx{Start: start}, nil
+	}
+	href, href_exists := l1StateBatchTx_el.Attr("href")
+	if !href_exists {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("l1StateBatchTx href not found"))
+	}
+
+	return L2Tx{Start: start, Href: href}, nil
+}
+
+func (s *HtmlL2) queryNotFound() bool {
+	return s.Contains("unable to locate this TxnHash")
+}
+
+// Scrapes the L1 explorer tx page
+type HtmlRoot struct {
+	*browser.Browser
+	selectors chain.Selectors
+}
+
+func NewHtmlRoot(root_chain_id chain.ChainId) (*HtmlRoot, error) {
+	c, c_err := chain.Get(root_chain_id)
+	if c_err != nil {
+		return nil, c_err
+	}
+	if c.Selectors.Timestamp == "" {
+		return nil, fmt.Errorf("No explorer selectors on chain %s, use SOURCE_%s=rpc", c.Id, c.Id)
+	}
+	return &HtmlRoot{
+		Browser:   browser.NewBrowser(),
+		selectors: c.Selectors,
+	}, nil
+}
+
+func (s *HtmlRoot) RootTx(root_l2_hash chain.RootL2Hash) (chain.RootTx, error) {
+	href := root_l2_hash.Href
+	open_err := s.Open(root_l2_hash.Ctx, href)
+	if open_err != nil {
+		return chain.RootTx{}, open_err
+	}
+
+	root_end, root_end_err := parseTs(s.First(s.selectors.Timestamp))
+	if root_end_err != nil {
+		if s.queryPending() {
+			return chain.RootTx{}, status.NewErr(status.ErrCodeL1Unconfirmed, fmt.Errorf("L1 tx pending: %s", href))
+		}
+		return chain.RootTx{}, status.NewErr(status.ErrCodeScrapeFailed, root_end_err)
+	}
+
+	root_tx := chain.RootTx{
+		RootEnd:     root_end,
+		RootBlock:   s.queryBlock(),
+		PostingMode: chain.PostingModeCalldata,
+	}
+	// "Txn Type: 3 (EIP-4844)", with a link per blob
+	if s.Contains("EIP-4844") {
+		root_tx.PostingMode = chain.PostingModeBlob
+		root_tx.BlobHashes = s.queryBlobHashes()
+	}
+	return root_tx, nil
+}
+
+// 0 if the block link is missing
+func (s *HtmlRoot) queryBlock() uint64 {
+	block_el := s.First("a[href^='/block/']")
+	if block_el == nil {
+		return 0
+	}
+	href, _ := block_el.Attr("href")
+	block, _ := strconv.ParseUint(path.Base(href), 10, 64)
+	return block
+}
+
+func (s *HtmlRoot) queryBlobHashes() []string {
+	hashes := []string{}
+	s.Find("a[href*='/blob/0x']").Each(func(_ int, a_el *goquery.Selection) {
+		href, _ := a_el.Attr("href")
+		hash := path.Base(href)
+		for _, known := range hashes {
+			if known == hash {
+				return
+			}
+		}
+		hashes = append(hashes, hash)
+	})
+	return hashes
+}
+
+// L1 tx not mined yet, or not indexed by the explorer yet
+func (s *HtmlRoot) queryPending() bool {
+	return s.Contains("(Pending)") || s.Contains("unable to locate this TxnHash")
+}
+
+// unix ms
+func parseTs(ts_el *goquery.Selection) (int64, error) {
+	if ts_el == nil {
+		return 0, fmt.Errorf("Timestamp el not found")
+	}
+
+	ts, ts_err := parse.Date(ts_el.Text())
+	if ts_err != nil {
+		return 0, fmt.Errorf("Error parsing timestamp: %w", ts_err)
+	}
+
+	return ts.UnixMilli(), nil
+}
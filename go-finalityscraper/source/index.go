@@ -0,0 +1,137 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"os"
+)
+
+// Where l2_b & root_b read chain data from
+type Kind string
+
+const (
+	// Explorer pages, see html.go
+	KindHtml Kind = "html"
+	// Nodes, see rpc.go
+	KindRpc Kind = "rpc"
+	// Explorer APIs, see etherscan.go
+	KindEtherscan Kind = "etherscan"
+)
+
+type L2Tx struct {
+	// L2 timestamp, unix ms
+	Start int64
+	// L1 batch tx, empty while not batched
+	Href string
+}
+
+// Used by l2_b, each B has its own
+type L2Source interface {
+	L2Tx(l2_hash chain.L2Hash) (L2Tx, error)
+}
+
+// Used by root_b, each B has its own
+type RootSource interface {
+	// L1 timestamp & posting mode of root_l2_hash.Href
+	RootTx(root_l2_hash chain.RootL2Hash) (chain.RootTx, error)
+}
+
+// .env SOURCE_<chain_id>, html by default
+func EnvKind(chain_id chain.ChainId) (Kind, error) {
+	kind := Kind(os.Getenv("SOURCE_" + string(chain_id)))
+	switch kind {
+	case "":
+		return KindHtml, nil
+	case KindHtml, KindRpc, KindEtherscan:
+		return kind, nil
+	default:
+		return "", fmt.Errorf("Unknown source for chain %s: %s", chain_id, kind)
+	}
+}
+
+// .env RPC_URL_<chain_id>
+func EnvRpcUrl(chain_id chain.ChainId) (string, error) {
+	url := os.Getenv("RPC_URL_" + string(chain_id))
+	if url == "" {
+		return "", fmt.Errorf("RPC_URL_%s not set", chain_id)
+	}
+	return url, nil
+}
+
+func NewL2Source(chain_id chain.ChainId) (L2Source, error) {
+	kind, kind_err := EnvKind(chain_id)
+	if kind_err != nil {
+		return nil, kind_err
+	}
+	switch kind {
+	case KindRpc:
+		c, c_err := chain.Get(chain_id)
+		if c_err == nil && c.Stack == chain.StackZkSync {
+			return NewZkSyncL2(chain_id)
+		}
+		if c_err == nil && c.Stack == chain.StackStarknet {
+			return NewStarknetL2(chain_id)
+		}
+		if c_err == nil && c.Stack == chain.StackPolygonZkEvm {
+			return NewZkEvmL2(chain_id)
+		}
+		return NewRpcL2(chain_id)
+	case KindEtherscan:
+		return NewEtherscanL2(chain_id)
+	default:
+		return NewHtmlL2(), nil
+	}
+}
+
+// root_chain_id as mapped by chain.MapChainIdRoot
+func NewRootSource(root_chain_id chain.ChainId) (RootSource, error) {
+	kind, kind_err := EnvKind(root_chain_id)
+	if kind_err != nil {
+		return nil, kind_err
+	}
+	switch kind {
+	case KindRpc:
+		return NewRpcRoot(root_chain_id)
+	case KindEtherscan:
+		return NewEtherscanRoot(root_chain_id)
+	default:
+		return NewHtmlRoot(root_chain_id)
+	}
+}
+
+// Sources of one B, created on first use per chain
+type L2Sources map[chain.ChainId]L2Source
+
+func (sources L2Sources) Get(chain_id chain.ChainId) (L2Source, error) {
+	src, src_exists := sources[chain_id]
+	if src_exists {
+		return src, nil
+	}
+	src, src_err := NewL2Source(chain_id)
+	if src_err != nil {
+		return nil, status.NewErr(status.ErrCodeUnknown, src_err)
+	}
+	sources[chain_id] = src
+	return src, nil
+}
+
+type RootSources map[chain.ChainId]RootSource
+
+// chain_id of the L2, the source is the one of its root chain
+func (sources RootSources) Get(chain_id chain.ChainId) (RootSource, error) {
+	root_chain_id, root_chain_id_err := chain.MapChainIdRoot(chain_id)
+	if root_chain_id_err != nil {
+		return nil, status.NewErr(status.ErrCodeUnknownChain, root_chain_id_err)
+	}
+	src, src_exists := sources[root_chain_id]
+	if src_exists {
+		return src, nil
+	}
+	src, src_err := NewRootSource(root_chain_id)
+	if src_err != nil {
+		return nil, status.NewErr(status.ErrCodeUnknown, src_err)
+	}
+	sources[root_chain_id] = src
+	return src, nil
+}
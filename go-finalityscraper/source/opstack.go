@@ -0,0 +1,85 @@
+package source
+
+import (
+	"context"
+	"go-finalityscraper/abi"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/rpc"
+	"strings"
+	"sync"
+)
+
+// SystemConfig.batcherHash(), the batcher address as bytes32
+const selector_batcher_hash = "0xe81b2c6d"
+
+// Sender of the batches of an OP Stack chain. The derivation ignores inbox txs
+// from anyone else, so they are skipped as well. Read from SystemConfig on first use,
+// unless configured, so a batcher rotated since is only picked up on restart.
+type opBatcher struct {
+	root          *rpc.Eth
+	system_config string
+
+	mu *sync.Mutex
+	// Empty until known
+	addr string
+}
+
+// OP Stack config of chain_id, nil for other stacks
+func mapOpStack(chain_id chain.ChainId) *chain.OpStack {
+	op_stack, op_stack_err := chain.MapChainIdOpStack(chain_id)
+	if op_stack_err != nil {
+		return nil
+	}
+	return &op_stack
+}
+
+// nil if op_stack is, or knows neither the batcher nor SystemConfig
+func newOpBatcher(root rpc.Caller, op_stack *chain.OpStack) *opBatcher {
+	if op_stack == nil || (op_stack.Batcher == "" && op_stack.SystemConfig == "") {
+		return nil
+	}
+	return &opBatcher{
+		root:          rpc.NewEth(root),
+		system_config: op_stack.SystemConfig,
+		mu:            &sync.Mutex{},
+		addr:          op_stack.Batcher,
+	}
+}
+
+// Whether from may send batches, always true without a batcher
+func (b *opBatcher) allows(ctx context.Context, from string) (bool, error) {
+	if b == nil {
+		return true, nil
+	}
+	addr, addr_err := b.get(ctx)
+	if addr_err != nil {
+		return false, addr_err
+	}
+	return strings.EqualFold(addr, from), nil
+}
+
+func (b *opBatcher) get(ctx context.Context) (string, error) {
+	b.mu.Lock()
+	addr := b.addr
+	b.mu.Unlock()
+	if addr != "" {
+		return addr, nil
+	}
+
+	result, result_err := b.root.CallContract(ctx, b.system_config, selector_batcher_hash)
+	if result_err != nil {
+		return "", result_err
+	}
+	data, data_err := abi.DecodeHex(result)
+	if data_err != nil {
+		return "", data_err
+	}
+	addr, addr_err := abi.Address(data, 0)
+	if addr_err != nil {
+		return "", addr_err
+	}
+	b.mu.Lock()
+	b.addr = addr
+	b.mu.Unlock()
+	return addr, nil
+}
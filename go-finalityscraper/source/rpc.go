@@ -0,0 +1,226 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/batch"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"net/url"
+	"path"
+	"time"
+)
+
+// How far after the L2 block its batch is looked for, in blocks of the root chain
+// by its avg_block_time
+const batch_scan_time = time.Hour
+
+// Finds the batch tx of an L2 tx on the root chain
+type batchFinder interface {
+	// Hash of the batch tx of L2 block at start (unix ms), empty if not posted yet
+	findBatch(l2_hash chain.L2Hash, block uint64, start int64) (string, error)
+}
+
+// Whether the batch tx may contain block, see batch.DecodeTx.
+// Batches that cannot be decoded are assumed to.
+func mayContain(chain_id chain.ChainId, tx_type uint64, input string, block uint64) bool {
+	b, b_err := batch.DecodeTx(chain_id, tx_type, input, nil)
+	if b_err != nil || !b.Complete {
+		return true
+	}
+	return b.Blocks.Last >= block
+}
+
+// Reads the L2 tx from an L2 node, and finds its batch on an L1 node.
+// The batch is the first batch inbox tx at or after the L2 block
+// that does not only contain older blocks.
+type RpcL2 struct {
+	l2      *rpc.Eth
+	batches batchFinder
+	// Explorer of the root chain, for hrefs
+	root_url chain.ChainUrl
+}
+
+func NewRpcL2(chain_id chain.ChainId) (*RpcL2, error) {
+	root_chain_id, root_chain_id_err := chain.MapChainIdRoot(chain_id)
+	if root_chain_id_err != nil {
+		return nil, root_chain_id_err
+	}
+	root_url, root_url_err := chain.MapChainIdRootUrl(chain_id)
+	if root_url_err != nil {
+		return nil, root_url_err
+	}
+	inbox, inbox_err := chain.MapChainIdBatchInbox(chain_id)
+	if inbox_err != nil {
+		return nil, inbox_err
+	}
+	l2_rpc_url, l2_rpc_url_err := EnvRpcUrl(chain_id)
+	if l2_rpc_url_err != nil {
+		return nil, l2_rpc_url_err
+	}
+	root_rpc_url, root_rpc_url_err := EnvRpcUrl(root_chain_id)
+	if root_rpc_url_err != nil {
+		return nil, root_rpc_url_err
+	}
+	root_block_time, root_block_time_err := chain.MapChainIdAvgBlockTime(root_chain_id)
+	if root_block_time_err != nil {
+		return nil, root_block_time_err
+	}
+
+	root := rpc.NewClient(root_rpc_url)
+	return NewRpcL2With(rpc.NewClient(l2_rpc_url), root, root_block_time, root_url, inbox, mapOpStack(chain_id)), nil
+}
+
+// op_stack nil for other stacks
+func NewRpcL2With(l2 rpc.Caller, root rpc.Caller, root_block_time time.Duration, root_url chain.ChainUrl, inbox string, op_stack *chain.OpStack) *RpcL2 {
+	return &RpcL2{
+		l2: rpc.NewEth(l2),
+		batches: &rpcBatches{
+			root:        rpc.NewEth(root),
+			scan_blocks: chain.BlocksIn(batch_scan_time, root_block_time),
+			inbox:       inbox,
+			batcher:     newOpBatcher(root, op_stack),
+		},
+		root_url: root_url,
+	}
+}
+
+func (s *RpcL2) L2Tx(l2_hash chain.L2Hash) (L2Tx, error) {
+	ctx := l2_hash.Ctx
+	tx, tx_err := s.l2.GetTransactionByHash(ctx, l2_hash.Hash)
+	if tx_err != nil {
+		return L2Tx{}, tx_err
+	}
+	if tx == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeTxNotFound, fmt.Errorf("tx not found: %s", l2_hash.Hash))
+	}
+	if tx.BlockNumber == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeNotBatched, fmt.Errorf("L2 tx pending: %s", l2_hash.Hash))
+	}
+
+	block, block_err := s.l2.GetBlockByNumber(ctx, uint64(*tx.BlockNumber), false)
+	if block_err != nil {
+		return L2Tx{}, block_err
+	}
+	if block == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L2 block not found: %d", *tx.BlockNumber))
+	}
+	start := block.TimeMs()
+
+	batch_hash, batch_hash_err := s.batches.findBatch(l2_hash, uint64(*tx.BlockNumber), start)
+	if batch_hash_err != nil {
+		return L2Tx{}, batch_hash_err
+	}
+	if batch_hash == "" {
+		return L2Tx{Start: start}, nil
+	}
+	return L2Tx{Start: start, Href: string(s.root_url) + "/tx/" + batch_hash}, nil
+}
+
+// Scans root chain blocks for the first tx to inbox
+type rpcBatches struct {
+	root *rpc.Eth
+	// Root chain blocks in batch_scan_time
+	scan_blocks uint64
+	inbox       string
+	// nil if any sender is taken
+	batcher *opBatcher
+}
+
+func (s *rpcBatches) findBatch(l2_hash chain.L2Hash, l2_block uint64, start int64) (string, error) {
+	ctx := l2_hash.Ctx
+	from, from_err := s.root.BlockAtTime(ctx, start)
+	if from_err != nil {
+		return "", from_err
+	}
+	head, head_err := s.root.BlockNumber(ctx)
+	if head_err != nil {
+		return "", head_err
+	}
+
+	to := from + s.scan_blocks
+	for n := from; n < to && n <= head; n++ {
+		block, block_err := s.root.GetBlockByNumber(ctx, n, true)
+		if block_err != nil {
+			return "", block_err
+		}
+		if block == nil {
+			break
+		}
+		for _, tx := range block.Transactions {
+			if !tx.IsTo(s.inbox) {
+				continue
+			}
+			allowed, allowed_err := s.batcher.allows(ctx, tx.From)
+			if allowed_err != nil {
+				return "", allowed_err
+			}
+			if allowed && mayContain(l2_hash.ChainId, uint64(tx.Type), tx.Input, l2_block) {
+				return tx.Hash, nil
+			}
+		}
+	}
+	if to > head {
+		return "", nil
+	}
+	return "", status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("No batch within %d blocks of %s", s.scan_blocks, l2_hash.Hash))
+}
+
+// Reads the L1 batch tx from an L1 node
+type RpcRoot struct {
+	root *rpc.Eth
+}
+
+func NewRpcRoot(root_chain_id chain.ChainId) (*RpcRoot, error) {
+	root_rpc_url, root_rpc_url_err := EnvRpcUrl(root_chain_id)
+	if root_rpc_url_err != nil {
+		return nil, root_rpc_url_err
+	}
+	return NewRpcRootWith(rpc.NewClient(root_rpc_url)), nil
+}
+
+func NewRpcRootWith(root rpc.Caller) *RpcRoot {
+	return &RpcRoot{
+		root: rpc.NewEth(root),
+	}
+}
+
+func (s *RpcRoot) RootTx(root_l2_hash chain.RootL2Hash) (chain.RootTx, error) {
+	ctx := root_l2_hash.Ctx
+	href := root_l2_hash.Href
+	hash, hash_err := HrefHash(href)
+	if hash_err != nil {
+		return chain.RootTx{}, status.NewErr(status.ErrCodeScrapeFailed, hash_err)
+	}
+
+	tx, tx_err := s.root.GetTransactionByHash(ctx, hash)
+	if tx_err != nil {
+		return chain.RootTx{}, tx_err
+	}
+	if tx == nil || tx.BlockNumber == nil {
+		return chain.RootTx{}, status.NewErr(status.ErrCodeL1Unconfirmed, fmt.Errorf("L1 tx pending: %s", href))
+	}
+
+	block, block_err := s.root.GetBlockByNumber(ctx, uint64(*tx.BlockNumber), false)
+	if block_err != nil {
+		return chain.RootTx{}, block_err
+	}
+	if block == nil {
+		return chain.RootTx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L1 block not found: %d", *tx.BlockNumber))
+	}
+	return chain.RootTx{
+		RootEnd:     block.TimeMs(),
+		RootBlock:   uint64(*tx.BlockNumber),
+		PostingMode: chain.PostingModeOf(uint64(tx.Type)),
+		BlobHashes:  tx.BlobVersionedHashes,
+	}, nil
+}
+
+// Tx hash of an explorer tx href
+func HrefHash(href string) (string, error) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", fmt.Errorf("Invalid href %s: %w", href, err)
+	}
+	return path.Base(u.Path), nil
+}
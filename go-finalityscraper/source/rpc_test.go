@@ -0,0 +1,242 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const test_inbox = "0xff00000000000000000000000000000000000010"
+
+// Of the root chain, as the stub blocks
+const test_block_time = 12 * time.Second
+
+// JSON-RPC method -> result, nil results are null
+type rpcHandlers map[string]func(params []json.RawMessage) any
+
+// Local stand-in for a node, unknown methods fail the test
+func newRpcServer(t *testing.T, handlers rpcHandlers) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			Id     uint64            `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}{}
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			t.Errorf("Invalid request: %s", err)
+			return
+		}
+		handler, handler_exists := handlers[req.Method]
+		if !handler_exists {
+			t.Errorf("Unexpected call: %s", req.Method)
+			return
+		}
+		res := map[string]any{"jsonrpc": "2.0", "id": req.Id}
+		result := handler(req.Params)
+		rpc_err, rpc_err_ok := result.(*rpc.Error)
+		if rpc_err_ok {
+			res["error"] = rpc_err
+		} else {
+			res["result"] = result
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Block number of eth_getBlockByNumber params
+func paramBlock(t *testing.T, params []json.RawMessage) uint64 {
+	s := ""
+	json.Unmarshal(params[0], &s)
+	n, err := rpc.ParseQuantity(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func quantity(n uint64) string {
+	return rpc.Quantity(n).String()
+}
+
+func testL2Hash(hash string) chain.L2Hash {
+	return chain.L2Hash{
+		Ctx:     context.Background(),
+		ChainId: "10",
+		Hash:    hash,
+	}
+}
+
+// L2 node with tx 0xa at block 16, at 1000s
+func newL2Server(t *testing.T, tx any) *httptest.Server {
+	return newRpcServer(t, rpcHandlers{
+		"eth_getTransactionByHash": func(params []json.RawMessage) any {
+			return tx
+		},
+		"eth_getBlockByNumber": func(params []json.RawMessage) any {
+			return map[string]any{"number": quantity(paramBlock(t, params)), "timestamp": quantity(1000)}
+		},
+	})
+}
+
+// L1 node with blocks 0..head every 12s from 988s, and an inbox tx in block batch_block
+func newRootServer(t *testing.T, head uint64, batch_block uint64) *httptest.Server {
+	return newRpcServer(t, rpcHandlers{
+		"eth_blockNumber": func(params []json.RawMessage) any {
+			return quantity(head)
+		},
+		"eth_getBlockByNumber": func(params []json.RawMessage) any {
+			n := paramBlock(t, params)
+			if n > head {
+				return nil
+			}
+			txs := []map[string]any{
+				{"hash": "0xother", "from": "0x1", "to": "0x2", "input": "0x", "type": "0x2"},
+			}
+			if n == batch_block {
+				txs = append(txs, map[string]any{"hash": "0xbatch", "from": "0x1", "to": test_inbox, "input": "0x00", "type": "0x2"})
+			}
+			return map[string]any{"number": quantity(n), "timestamp": quantity(988 + 12*n), "transactions": txs}
+		},
+	})
+}
+
+func TestRpcL2Tx(t *testing.T) {
+	l2_tx := map[string]any{"hash": "0xa", "blockNumber": quantity(16)}
+	tests := []struct {
+		name string
+		tx   any
+		head uint64
+		// Block of the inbox tx
+		batch_block uint64
+		want        L2Tx
+		want_code   status.ErrCode
+	}{
+		{name: "batch found", tx: l2_tx, head: 10, batch_block: 3, want: L2Tx{Start: 1_000_000, Href: "https://etherscan.io/tx/0xbatch"}},
+		{name: "not batched yet", tx: l2_tx, head: 10, batch_block: 20, want: L2Tx{Start: 1_000_000}},
+		{name: "tx pending", tx: map[string]any{"hash": "0xa", "blockNumber": nil}, head: 10, want_code: status.ErrCodeNotBatched},
+		{name: "tx not found", tx: nil, head: 10, want_code: status.ErrCodeTxNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l2 := newL2Server(t, tt.tx)
+			root := newRootServer(t, tt.head, tt.batch_block)
+			s := NewRpcL2With(rpc.NewClient(l2.URL), rpc.NewClient(root.URL), test_block_time, "https://etherscan.io", test_inbox, nil)
+
+			got, err := s.L2Tx(testL2Hash("0xa"))
+			if tt.want_code != "" {
+				if status.CodeOf(err) != tt.want_code {
+					t.Fatalf("got error %v, want %s", err, tt.want_code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRpcL2TxRpcError(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	failing := newRpcServer(t, rpcHandlers{
+		"eth_getTransactionByHash": func(params []json.RawMessage) any {
+			return &rpc.Error{Code: -32603, Message: "internal error"}
+		},
+	})
+	malformed := newRpcServer(t, rpcHandlers{
+		"eth_getTransactionByHash": func(params []json.RawMessage) any {
+			return "not a tx"
+		},
+	})
+	root := newRootServer(t, 10, 3)
+
+	s := NewRpcL2With(rpc.NewClient(unavailable.URL), rpc.NewClient(root.URL), test_block_time, "https://etherscan.io", test_inbox, nil)
+	_, err := s.L2Tx(testL2Hash("0xa"))
+	if status.CodeOf(err) != status.ErrCodeExplorerUnavailable {
+		t.Fatalf("got error %v, want %s", err, status.ErrCodeExplorerUnavailable)
+	}
+
+	s = NewRpcL2With(rpc.NewClient(failing.URL), rpc.NewClient(root.URL), test_block_time, "https://etherscan.io", test_inbox, nil)
+	_, err = s.L2Tx(testL2Hash("0xa"))
+	var rpc_err *rpc.Error
+	if !errors.As(err, &rpc_err) || rpc_err.Code != -32603 {
+		t.Fatalf("got error %v, want the RPC error", err)
+	}
+	if !status.CodeOf(err).Retry() {
+		t.Fatalf("got error %v, want one to retry", err)
+	}
+
+	s = NewRpcL2With(rpc.NewClient(malformed.URL), rpc.NewClient(root.URL), test_block_time, "https://etherscan.io", test_inbox, nil)
+	_, err = s.L2Tx(testL2Hash("0xa"))
+	if status.CodeOf(err) != status.ErrCodeScrapeFailed {
+		t.Fatalf("got error %v, want %s", err, status.ErrCodeScrapeFailed)
+	}
+}
+
+func TestRpcRootTx(t *testing.T) {
+	tests := []struct {
+		name      string
+		tx        any
+		want      chain.RootTx
+		want_code status.ErrCode
+	}{
+		{
+			name: "calldata",
+			tx:   map[string]any{"hash": "0xbatch", "blockNumber": quantity(3), "type": "0x2"},
+			want: chain.RootTx{RootEnd: 1_024_000, RootBlock: 3, PostingMode: chain.PostingModeCalldata},
+		},
+		{
+			name: "blobs",
+			tx:   map[string]any{"hash": "0xbatch", "blockNumber": quantity(3), "type": "0x3", "blobVersionedHashes": []string{"0x01aa"}},
+			want: chain.RootTx{RootEnd: 1_024_000, RootBlock: 3, PostingMode: chain.PostingModeBlob, BlobHashes: []string{"0x01aa"}},
+		},
+		{name: "tx pending", tx: map[string]any{"hash": "0xbatch", "blockNumber": nil}, want_code: status.ErrCodeL1Unconfirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newRpcServer(t, rpcHandlers{
+				"eth_getTransactionByHash": func(params []json.RawMessage) any {
+					return tt.tx
+				},
+				"eth_getBlockByNumber": func(params []json.RawMessage) any {
+					n := paramBlock(t, params)
+					return map[string]any{"number": quantity(n), "timestamp": quantity(988 + 12*n)}
+				},
+			})
+			s := NewRpcRootWith(rpc.NewClient(root.URL))
+
+			got, err := s.RootTx(chain.RootL2Hash{
+				L2Hash: testL2Hash("0xa"),
+				Href:   "https://etherscan.io/tx/0xbatch",
+			})
+			if tt.want_code != "" {
+				if status.CodeOf(err) != tt.want_code {
+					t.Fatalf("got error %v, want %s", err, tt.want_code)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.RootEnd != tt.want.RootEnd || got.RootBlock != tt.want.RootBlock || got.PostingMode != tt.want.PostingMode || len(got.BlobHashes) != len(tt.want.BlobHashes) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
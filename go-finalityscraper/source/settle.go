@@ -0,0 +1,90 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"go-finalityscraper/settle"
+	"sync"
+)
+
+// nil if chain_id has no settlement to track, or its root chain (the L2 itself for zkSync, both for Polygon zkEVM) no RPC_URL_<chain_id>
+func NewSettler(chain_id chain.ChainId) (settle.Settler, error) {
+	c, c_err := chain.Get(chain_id)
+	if c_err != nil || !c.IsL2() {
+		return nil, status.NewErr(status.ErrCodeUnknownChain, fmt.Errorf("Unknown chain id: %s", chain_id))
+	}
+	// Read from the L2 node
+	if c.Finality.Model == chain.FinalityZkSync {
+		l2_url, l2_url_err := EnvRpcUrl(chain_id)
+		if l2_url_err != nil {
+			return nil, nil
+		}
+		return settle.NewZkSyncBatches(rpc.NewClient(l2_url)), nil
+	}
+
+	root_url, root_url_err := EnvRpcUrl(c.Parent)
+	if root_url_err != nil {
+		return nil, nil
+	}
+	root_block_time, root_block_time_err := chain.MapChainIdAvgBlockTime(c.Parent)
+	if root_block_time_err != nil {
+		return nil, root_block_time_err
+	}
+	switch c.Finality.Model {
+	case chain.FinalityDisputeGames:
+		genesis, genesis_err := chain.MapChainIdGenesis(chain_id)
+		if genesis_err != nil {
+			return nil, genesis_err
+		}
+		return settle.NewOptimismGames(rpc.NewClient(root_url), root_block_time, *c.Finality.DisputeGames, genesis), nil
+	case chain.FinalityOutputOracle:
+		genesis, genesis_err := chain.MapChainIdGenesis(chain_id)
+		if genesis_err != nil {
+			return nil, genesis_err
+		}
+		return settle.NewOutputOracle(rpc.NewClient(root_url), root_block_time, *c.Finality.OutputOracle, genesis, c.Finality.ChallengePeriod.Duration), nil
+	case chain.FinalityRollup:
+		return settle.NewArbitrumRollup(rpc.NewClient(root_url), root_block_time, *c.Finality.Rollup), nil
+	case chain.FinalityStarknet:
+		return settle.NewStarknetCore(rpc.NewClient(root_url), root_block_time, *c.Finality.StarknetCore), nil
+	case chain.FinalityZkEvm:
+		// Batches are read from the L2 node, their L1 txs from the root chain
+		l2_url, l2_url_err := EnvRpcUrl(chain_id)
+		if l2_url_err != nil {
+			return nil, nil
+		}
+		return settle.NewZkEvmBatches(rpc.NewClient(l2_url), rpc.NewClient(root_url)), nil
+	default:
+		return nil, nil
+	}
+}
+
+// Settlers per L2 chain, shared by every root_b so claims are only read once
+type Settlers struct {
+	mu *sync.Mutex
+	m  map[chain.ChainId]settle.Settler
+}
+
+func NewSettlers() *Settlers {
+	return &Settlers{
+		mu: &sync.Mutex{},
+		m:  map[chain.ChainId]settle.Settler{},
+	}
+}
+
+func (settlers *Settlers) Get(chain_id chain.ChainId) (settle.Settler, error) {
+	settlers.mu.Lock()
+	defer settlers.mu.Unlock()
+	settler, settler_exists := settlers.m[chain_id]
+	if settler_exists {
+		return settler, nil
+	}
+	settler, settler_err := NewSettler(chain_id)
+	if settler_err != nil {
+		return nil, settler_err
+	}
+	settlers.m[chain_id] = settler
+	return settler, nil
+}
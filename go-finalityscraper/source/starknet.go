@@ -0,0 +1,96 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+	"go-finalityscraper/settle"
+	"time"
+)
+
+// Reads the L2 tx from a Starknet node. Its batch is the L1 state update covering
+// its block, found on the root chain node once the tx is ACCEPTED_ON_L1.
+type StarknetL2 struct {
+	l2   *rpc.Starknet
+	root *rpc.Eth
+	core *settle.StarknetCore
+	// Explorer of the root chain, for hrefs
+	root_url chain.ChainUrl
+}
+
+func NewStarknetL2(chain_id chain.ChainId) (*StarknetL2, error) {
+	c, c_err := chain.Get(chain_id)
+	if c_err != nil {
+		return nil, c_err
+	}
+	if c.Finality.StarknetCore == nil {
+		return nil, fmt.Errorf("No core contract on chain: %s", chain_id)
+	}
+	root_url, root_url_err := chain.MapChainIdRootUrl(chain_id)
+	if root_url_err != nil {
+		return nil, root_url_err
+	}
+	l2_rpc_url, l2_rpc_url_err := EnvRpcUrl(chain_id)
+	if l2_rpc_url_err != nil {
+		return nil, l2_rpc_url_err
+	}
+	root_rpc_url, root_rpc_url_err := EnvRpcUrl(c.Parent)
+	if root_rpc_url_err != nil {
+		return nil, root_rpc_url_err
+	}
+	root_block_time, root_block_time_err := chain.MapChainIdAvgBlockTime(c.Parent)
+	if root_block_time_err != nil {
+		return nil, root_block_time_err
+	}
+	return NewStarknetL2With(rpc.NewClient(l2_rpc_url), rpc.NewClient(root_rpc_url), root_block_time, root_url, *c.Finality.StarknetCore), nil
+}
+
+func NewStarknetL2With(l2 rpc.Caller, root rpc.Caller, root_block_time time.Duration, root_url chain.ChainUrl, core chain.StarknetCore) *StarknetL2 {
+	return &StarknetL2{
+		l2:       rpc.NewStarknet(l2),
+		root:     rpc.NewEth(root),
+		core:     settle.NewStarknetCore(root, root_block_time, core),
+		root_url: root_url,
+	}
+}
+
+func (s *StarknetL2) L2Tx(l2_hash chain.L2Hash) (L2Tx, error) {
+	ctx := l2_hash.Ctx
+	receipt, receipt_err := s.l2.GetTransactionReceipt(ctx, l2_hash.Hash)
+	if receipt_err != nil {
+		return L2Tx{}, receipt_err
+	}
+	if receipt == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeTxNotFound, fmt.Errorf("tx not found: %s", l2_hash.Hash))
+	}
+	if receipt.BlockNumber == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeNotBatched, fmt.Errorf("L2 tx pending: %s", l2_hash.Hash))
+	}
+
+	block, block_err := s.l2.GetBlockWithTxHashes(ctx, *receipt.BlockNumber)
+	if block_err != nil {
+		return L2Tx{}, block_err
+	}
+	if block == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L2 block not found: %d", *receipt.BlockNumber))
+	}
+	start := block.TimeMs()
+	// No state update covers it yet
+	if receipt.FinalityStatus != rpc.StarknetAcceptedOnL1 {
+		return L2Tx{Start: start}, nil
+	}
+
+	from, from_err := s.root.BlockAtTime(ctx, start)
+	if from_err != nil {
+		return L2Tx{}, from_err
+	}
+	update_hash, update_hash_err := s.core.StateUpdate(ctx, *receipt.BlockNumber, from)
+	if update_hash_err != nil {
+		return L2Tx{}, update_hash_err
+	}
+	if update_hash == "" {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("No state update found for %s", l2_hash.Hash))
+	}
+	return L2Tx{Start: start, Href: string(s.root_url) + "/tx/" + update_hash}, nil
+}
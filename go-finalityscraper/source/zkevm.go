@@ -0,0 +1,74 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+)
+
+// Reads the L2 tx from a Polygon zkEVM node. Its batch tx is the L1 tx sequencing
+// its batch, when it became virtual.
+type ZkEvmL2 struct {
+	l2    *rpc.Eth
+	zkevm *rpc.ZkEvm
+	// Explorer of the root chain, for hrefs
+	root_url chain.ChainUrl
+}
+
+func NewZkEvmL2(chain_id chain.ChainId) (*ZkEvmL2, error) {
+	root_url, root_url_err := chain.MapChainIdRootUrl(chain_id)
+	if root_url_err != nil {
+		return nil, root_url_err
+	}
+	l2_rpc_url, l2_rpc_url_err := EnvRpcUrl(chain_id)
+	if l2_rpc_url_err != nil {
+		return nil, l2_rpc_url_err
+	}
+	return NewZkEvmL2With(rpc.NewClient(l2_rpc_url), root_url), nil
+}
+
+func NewZkEvmL2With(l2 rpc.Caller, root_url chain.ChainUrl) *ZkEvmL2 {
+	return &ZkEvmL2{
+		l2:       rpc.NewEth(l2),
+		zkevm:    rpc.NewZkEvm(l2),
+		root_url: root_url,
+	}
+}
+
+func (s *ZkEvmL2) L2Tx(l2_hash chain.L2Hash) (L2Tx, error) {
+	ctx := l2_hash.Ctx
+	tx, tx_err := s.l2.GetTransactionByHash(ctx, l2_hash.Hash)
+	if tx_err != nil {
+		return L2Tx{}, tx_err
+	}
+	if tx == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeTxNotFound, fmt.Errorf("tx not found: %s", l2_hash.Hash))
+	}
+	if tx.BlockNumber == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeNotBatched, fmt.Errorf("L2 tx pending: %s", l2_hash.Hash))
+	}
+
+	block, block_err := s.l2.GetBlockByNumber(ctx, uint64(*tx.BlockNumber), false)
+	if block_err != nil {
+		return L2Tx{}, block_err
+	}
+	if block == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L2 block not found: %d", *tx.BlockNumber))
+	}
+	start := block.TimeMs()
+
+	batch_number, batch_number_err := s.zkevm.BatchNumberByBlockNumber(ctx, uint64(*tx.BlockNumber))
+	if batch_number_err != nil {
+		return L2Tx{}, batch_number_err
+	}
+	batch, batch_err := s.zkevm.GetBatchByNumber(ctx, batch_number)
+	if batch_err != nil {
+		return L2Tx{}, batch_err
+	}
+	// Trusted only, not sequenced on L1 yet
+	if batch == nil || batch.SequenceTx() == "" {
+		return L2Tx{Start: start}, nil
+	}
+	return L2Tx{Start: start, Href: string(s.root_url) + "/tx/" + batch.SequenceTx()}, nil
+}
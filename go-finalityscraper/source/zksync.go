@@ -0,0 +1,69 @@
+package source
+
+import (
+	"fmt"
+	"go-finalityscraper/common/chain"
+	"go-finalityscraper/common/status"
+	"go-finalityscraper/rpc"
+)
+
+// Reads the L2 tx from a zkSync Era node. Its batch is the L1 tx committing
+// the L1 batch of the tx, zks_getTransactionDetails knows it.
+type ZkSyncL2 struct {
+	l2  *rpc.Eth
+	zks *rpc.Zks
+	// Explorer of the root chain, for hrefs
+	root_url chain.ChainUrl
+}
+
+func NewZkSyncL2(chain_id chain.ChainId) (*ZkSyncL2, error) {
+	root_url, root_url_err := chain.MapChainIdRootUrl(chain_id)
+	if root_url_err != nil {
+		return nil, root_url_err
+	}
+	l2_rpc_url, l2_rpc_url_err := EnvRpcUrl(chain_id)
+	if l2_rpc_url_err != nil {
+		return nil, l2_rpc_url_err
+	}
+	return NewZkSyncL2With(rpc.NewClient(l2_rpc_url), root_url), nil
+}
+
+func NewZkSyncL2With(l2 rpc.Caller, root_url chain.ChainUrl) *ZkSyncL2 {
+	return &ZkSyncL2{
+		l2:       rpc.NewEth(l2),
+		zks:      rpc.NewZks(l2),
+		root_url: root_url,
+	}
+}
+
+func (s *ZkSyncL2) L2Tx(l2_hash chain.L2Hash) (L2Tx, error) {
+	ctx := l2_hash.Ctx
+	tx, tx_err := s.l2.GetTransactionByHash(ctx, l2_hash.Hash)
+	if tx_err != nil {
+		return L2Tx{}, tx_err
+	}
+	if tx == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeTxNotFound, fmt.Errorf("tx not found: %s", l2_hash.Hash))
+	}
+	if tx.BlockNumber == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeNotBatched, fmt.Errorf("L2 tx pending: %s", l2_hash.Hash))
+	}
+
+	block, block_err := s.l2.GetBlockByNumber(ctx, uint64(*tx.BlockNumber), false)
+	if block_err != nil {
+		return L2Tx{}, block_err
+	}
+	if block == nil {
+		return L2Tx{}, status.NewErr(status.ErrCodeScrapeFailed, fmt.Errorf("L2 block not found: %d", *tx.BlockNumber))
+	}
+	start := block.TimeMs()
+
+	details, details_err := s.zks.GetTransactionDetails(ctx, l2_hash.Hash)
+	if details_err != nil {
+		return L2Tx{}, details_err
+	}
+	if details == nil || details.EthCommitTxHash == nil {
+		return L2Tx{Start: start}, nil
+	}
+	return L2Tx{Start: start, Href: string(s.root_url) + "/tx/" + *details.EthCommitTxHash}, nil
+}
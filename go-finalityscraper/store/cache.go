@@ -0,0 +1,75 @@
+package store
+
+import (
+	"go-finalityscraper/common/chain"
+	"strings"
+	"sync/atomic"
+)
+
+const bucket_cache = "cache"
+
+// Finality results never change once known, so they are kept indefinitely
+type Cache struct {
+	s *Store
+
+	hits   *atomic.Uint64
+	misses *atomic.Uint64
+}
+
+type CacheStats struct {
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+	Entries int    `json:"entries"`
+}
+
+func NewCache(s *Store) *Cache {
+	return &Cache{
+		s: s,
+
+		hits:   &atomic.Uint64{},
+		misses: &atomic.Uint64{},
+	}
+}
+
+func cacheKey(chain_id chain.ChainId, hash string) string {
+	return string(chain_id) + ":" + strings.ToLower(hash)
+}
+
+// Not counted, callers may still reject the entry, see Count
+func (c *Cache) Get(chain_id chain.ChainId, hash string, v any) bool {
+	found, err := c.s.Get(bucket_cache, cacheKey(chain_id, hash), v)
+	return err == nil && found
+}
+
+// Counts a lookup, a hit once the entry was served
+func (c *Cache) Count(hit bool) {
+	if hit {
+		c.hits.Add(1)
+	} else {
+		c.misses.Add(1)
+	}
+}
+
+func (c *Cache) Set(chain_id chain.ChainId, hash string, v any) error {
+	return c.s.Put(bucket_cache, cacheKey(chain_id, hash), v)
+}
+
+// Invalidates one (chain, hash), or every hash of the chain if hash is empty
+func (c *Cache) Invalidate(chain_id chain.ChainId, hash string) (int, error) {
+	if hash == "" {
+		return c.s.DeletePrefix(bucket_cache, string(chain_id)+":")
+	}
+	deleted, err := c.s.Delete(bucket_cache, cacheKey(chain_id, hash))
+	if deleted {
+		return 1, err
+	}
+	return 0, err
+}
+
+func (c *Cache) Stats() CacheStats {
+	return CacheStats{
+		Hits:    c.hits.Load(),
+		Misses:  c.misses.Load(),
+		Entries: c.s.Len(bucket_cache),
+	}
+}
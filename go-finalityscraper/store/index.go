@@ -0,0 +1,127 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Embedded on-disk key -> JSON store, one bucket per kind of record
+type Store struct {
+	db *bolt.DB
+}
+
+func NewStore(path string) *Store {
+	db, err := bolt.Open(path, 0644, nil)
+	if err != nil {
+		panic(err)
+	}
+	return &Store{db}
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Unmarshals the value at key into v, false if there is none
+func (s *Store) Get(bucket string, key string, v any) (bool, error) {
+	var raw []byte
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		found := b.Get([]byte(key))
+		if found != nil {
+			raw = append(raw, found...)
+		}
+		return nil
+	})
+	if raw == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, v)
+}
+
+func (s *Store) Put(bucket string, key string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, bucket_err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if bucket_err != nil {
+			return bucket_err
+		}
+		return b.Put([]byte(key), raw)
+	})
+}
+
+// false if there was nothing at key
+func (s *Store) Delete(bucket string, key string) (bool, error) {
+	deleted := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil || b.Get([]byte(key)) == nil {
+			return nil
+		}
+		deleted = true
+		return b.Delete([]byte(key))
+	})
+	return deleted, err
+}
+
+// Deletes every key starting with prefix, returns how many were deleted
+func (s *Store) DeletePrefix(bucket string, prefix string) (int, error) {
+	deleted := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		keys := [][]byte{}
+		c := b.Cursor()
+		for k, _ := c.Seek([]byte(prefix)); k != nil && strings.HasPrefix(string(k), prefix); k, _ = c.Next() {
+			keys = append(keys, k)
+		}
+		for _, k := range keys {
+			del_err := b.Delete(k)
+			if del_err != nil {
+				return del_err
+			}
+			deleted++
+		}
+		return nil
+	})
+	return deleted, err
+}
+
+func (s *Store) Len(bucket string) int {
+	n := 0
+	s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b != nil {
+			n = b.Stats().KeyN
+		}
+		return nil
+	})
+	return n
+}
+
+// Calls fn with each key & JSON value of bucket until it returns false
+func (s *Store) Iter(bucket string, fn func(key string, raw []byte) bool) error {
+	return s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		c := b.Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if !fn(string(k), v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}